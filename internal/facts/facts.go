@@ -0,0 +1,65 @@
+package facts
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+)
+
+// Facts describe the host align is running on. They are exposed to templates
+// so that a single template can be rendered differently on every host.
+type Facts struct {
+	Hostname   string
+	CPUs       int
+	Interfaces map[string][]string
+}
+
+func Gather() (Facts, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return Facts{}, fmt.Errorf("failed to get hostname: %w", err)
+	}
+
+	interfaces, err := gatherInterfaces()
+	if err != nil {
+		return Facts{}, fmt.Errorf("failed to get interfaces: %w", err)
+	}
+
+	facts := Facts{
+		Hostname:   hostname,
+		CPUs:       runtime.NumCPU(),
+		Interfaces: interfaces,
+	}
+
+	return facts, nil
+}
+
+func gatherInterfaces() (map[string][]string, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interfaces: %w", err)
+	}
+
+	addrsByInterface := make(map[string][]string, len(interfaces))
+	for _, iface := range interfaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list interface's addresses: %w", err)
+		}
+
+		ips := make([]string, 0, len(addrs))
+		for _, addr := range addrs {
+			ip, _, err := net.ParseCIDR(addr.String())
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse interface's address: %w", err)
+			}
+
+			ips = append(ips, ip.String())
+		}
+
+		addrsByInterface[iface.Name] = ips
+	}
+
+	return addrsByInterface, nil
+}
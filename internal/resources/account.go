@@ -0,0 +1,149 @@
+package resources
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"github.com/scherepiuk/align/internal/types"
+)
+
+// AccountBackend reads and changes the local users and groups. There are two
+// of them: the shadow-utils one (see NewShadowUtilsBackend), which is used by
+// default, and the native one (see NewNativeBackend).
+type AccountBackend interface {
+	lookupPasswd(name string) (passwdEntry, error)
+	listPasswd() ([]passwdEntry, error)
+	lookupShadow(name string) (shadowEntry, error)
+	lookupGroup(name string) (groupEntry, error)
+	listGroups() ([]groupEntry, error)
+
+	addUser(name string, changes userChanges) error
+	modifyUser(name string, changes userChanges) error
+	deleteUser(name string, removeHome bool) error
+
+	addGroup(name string, changes groupChanges) error
+	modifyGroup(name string, changes groupChanges) error
+
+	// path resolves the path (e.g., the home directory) against the root the
+	// backend operates on.
+	path(path string) string
+}
+
+// userChanges lists what to set on the user. Absent optionals are left alone.
+type userChanges struct {
+	uid        types.Optional[int]
+	gid        types.Optional[int]
+	groups     types.Optional[[]string] // supplementary groups, replacing the current ones
+	addGroups  bool                     // groups are added to the current ones instead
+	home       types.Optional[string]
+	moveHome   bool
+	createHome bool
+	shell      types.Optional[string]
+	comment    types.Optional[string]
+	password   types.Optional[string] // set as is, including the lock prefix
+	locked     types.Optional[bool]
+	expired    types.Optional[bool]
+	maxAge     types.Optional[int] // password aging, in days
+	minAge     types.Optional[int]
+	warnDays   types.Optional[int]
+	expiry     types.Optional[int] // days since the epoch, overrides expired
+	system     bool
+}
+
+// groupChanges lists what to set on the group. Absent optionals are left alone.
+type groupChanges struct {
+	gid           types.Optional[int]
+	members       types.Optional[[]string] // replacing the current ones
+	addMembers    []string                 // added to the current ones
+	removeMembers []string                 // removed from the current ones
+	system        bool
+}
+
+// accountFiles reads the account databases under the root directory.
+type accountFiles struct {
+	root string
+}
+
+// aging tells whether any of the password aging fields is to be set.
+func (c userChanges) aging() bool {
+	return c.maxAge.Ok() || c.minAge.Ok() || c.warnDays.Ok() || c.expiry.Ok()
+}
+
+func (a accountFiles) path(path string) string {
+	return filepath.Join(a.root, path)
+}
+
+func (a accountFiles) lookupPasswd(name string) (passwdEntry, error) {
+	entries, err := readEntries(a.path(passwdPath), parsePasswdEntry)
+	if err != nil {
+		return passwdEntry{}, err
+	}
+
+	for _, entry := range entries {
+		if entry.name == name {
+			return entry, nil
+		}
+	}
+
+	return passwdEntry{}, user.UnknownUserError(name)
+}
+
+func (a accountFiles) listPasswd() ([]passwdEntry, error) {
+	return readEntries(a.path(passwdPath), parsePasswdEntry)
+}
+
+func (a accountFiles) lookupShadow(name string) (shadowEntry, error) {
+	entries, err := readEntries(a.path(shadowPath), parseShadowEntry)
+	if err != nil {
+		return shadowEntry{}, err
+	}
+
+	for _, entry := range entries {
+		if entry.name == name {
+			return entry, nil
+		}
+	}
+
+	return shadowEntry{}, user.UnknownUserError(name)
+}
+
+func (a accountFiles) lookupGroup(name string) (groupEntry, error) {
+	entries, err := readEntries(a.path(groupPath), parseGroupEntry)
+	if err != nil {
+		return groupEntry{}, err
+	}
+
+	for _, entry := range entries {
+		if entry.name == name {
+			return entry, nil
+		}
+	}
+
+	return groupEntry{}, user.UnknownGroupError(name)
+}
+
+func (a accountFiles) listGroups() ([]groupEntry, error) {
+	return readEntries(a.path(groupPath), parseGroupEntry)
+}
+
+// makeHome creates the home directory owned by the user, unless it exists.
+func makeHome(path string, uid, gid int) error {
+	_, err := os.Stat(path)
+	if err == nil {
+		return nil
+	}
+
+	err = os.MkdirAll(path, defaultHomeMode)
+	if err != nil {
+		return fmt.Errorf("failed to create home: %w", err)
+	}
+
+	err = os.Chown(path, uid, gid)
+	if err != nil {
+		return fmt.Errorf("failed to change home's owner: %w", err)
+	}
+
+	return nil
+}
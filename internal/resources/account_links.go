@@ -0,0 +1,99 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// LinkAccounts lets the resources managing the same group memberships know
+// about each other, so they don't fight over them. A User listed by
+// a GroupMembership keeps the group even if its groups are exact (see
+// WithMembership), the same as with WithManagedMembers. The memberships that
+// can't be reconciled (e.g., an exact GroupMembership leaving out a User
+// listing the group) are returned as an error. The members of a Group (see
+// WithMembers) are linked the same way as the ones of an exact
+// GroupMembership. Every User is managed as far as UnmanagedUsers is
+// concerned, whether it is listed there or not (see WithManagedUsers).
+func LinkAccounts(all ...Resource) error {
+	users := make([]*User, 0)
+	groups := make([]*Group, 0)
+	memberships := make([]*GroupMembership, 0)
+	unmanaged := make([]*UnmanagedUsers, 0)
+
+	for _, resource := range all {
+		switch resource := resource.(type) {
+		case *User:
+			users = append(users, resource)
+		case *Group:
+			groups = append(groups, resource)
+		case *GroupMembership:
+			memberships = append(memberships, resource)
+		case *UnmanagedUsers:
+			unmanaged = append(unmanaged, resource)
+		}
+	}
+
+	for _, unmanaged := range unmanaged {
+		WithManagedUsers(users...)(unmanaged)
+	}
+
+	errs := make([]error, 0)
+
+	for _, membership := range memberships {
+		for _, user := range users {
+			if slices.Contains(membership.members, user.name) {
+				user.claim(membership.group)
+				continue
+			}
+
+			if membership.membership == MembershipExact && slices.Contains(user.groups.Value(), membership.group) {
+				errs = append(errs, fmt.Errorf(
+					"user %s is in group %s, whose exact group membership leaves it out",
+					user.name, membership.group,
+				))
+			}
+		}
+	}
+
+	for _, group := range groups {
+		if !group.members.Ok() {
+			continue
+		}
+
+		members := group.members.Value()
+
+		for _, user := range users {
+			if slices.Contains(members, user.name) {
+				user.claim(group.name)
+				continue
+			}
+
+			if slices.Contains(user.groups.Value(), group.name) {
+				errs = append(errs, fmt.Errorf(
+					"user %s is in group %s, whose members leave it out",
+					user.name, group.name,
+				))
+			}
+		}
+
+		for _, membership := range memberships {
+			if membership.group != group.name {
+				continue
+			}
+
+			extra := slices.ContainsFunc(membership.members, func(member string) bool {
+				return !slices.Contains(members, member)
+			})
+			missing := membership.membership == MembershipExact && slices.ContainsFunc(members, func(member string) bool {
+				return !slices.Contains(membership.members, member)
+			})
+
+			if extra || missing {
+				errs = append(errs, fmt.Errorf("group membership of %s disagrees with the members of the group", group.name))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,44 @@
+//go:build linux
+
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockAccounts takes the same lock as lckpwdf(3), i.e., a write lock of the
+// whole lock file, so shadow-utils and align never edit the databases at the
+// same time. It gives up after the timeout.
+func lockAccounts(path string, timeout time.Duration) (func(), error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := unix.Flock_t{Type: unix.F_WRLCK, Whence: 0, Start: 0, Len: 0}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		err = unix.FcntlFlock(file.Fd(), unix.F_SETLK, &lock)
+		if err == nil {
+			return func() { file.Close() }, nil
+		}
+
+		if !errors.Is(err, unix.EAGAIN) && !errors.Is(err, unix.EACCES) {
+			file.Close()
+			return nil, err
+		}
+
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("lock is held by another process: %s", path)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package resources
+
+import (
+	"errors"
+	"time"
+)
+
+func lockAccounts(path string, timeout time.Duration) (func(), error) {
+	return nil, errors.ErrUnsupported
+}
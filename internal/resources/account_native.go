@@ -0,0 +1,592 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scherepiuk/align/internal/logger"
+	"github.com/scherepiuk/align/internal/types"
+)
+
+const (
+	accountsLockPath    = "/etc/.pwd.lock"
+	accountsLockTimeout = 15 * time.Second
+	defaultUserShell    = "/bin/sh"
+)
+
+// accountsMu serializes the edits of all the native backends. The lock of the
+// databases (see lockAccounts) is held by the process rather than by the edit,
+// so it does not keep the resources aligned at the same time apart.
+var accountsMu sync.Mutex
+
+// nativeBackend edits the account databases itself, so it works without
+// shadow-utils (e.g., on minimal images) and against an alternate root. The
+// databases are locked the same way shadow-utils locks them and every file is
+// replaced atomically.
+type nativeBackend struct {
+	accountFiles
+}
+
+func NewNativeBackend(root string) AccountBackend {
+	return &nativeBackend{accountFiles{root: root}}
+}
+
+func (b *nativeBackend) addUser(name string, changes userChanges) error {
+	if !changes.uid.Ok() || !changes.gid.Ok() {
+		return fmt.Errorf("failed to add user: uid and gid are required")
+	}
+
+	home := "/home/" + name
+	if changes.home.Ok() {
+		home = changes.home.Value()
+	}
+
+	shell := defaultUserShell
+	if changes.shell.Ok() {
+		shell = changes.shell.Value()
+	}
+
+	passwd := []string{
+		name, "x",
+		fmt.Sprint(changes.uid.Value()), fmt.Sprint(changes.gid.Value()),
+		changes.comment.Value(), home, shell,
+	}
+
+	// NOTE: Users without a password are locked, the same as with useradd.
+	shadow := []string{name, "!", fmt.Sprint(today()), "", "", "", "", "", ""}
+	changeShadowFields(shadow, changes)
+
+	edits := []accountsEdit{
+		{path: passwdPath, edit: addEntry(passwd)},
+		{path: shadowPath, edit: addEntry(shadow)},
+	}
+
+	if changes.groups.Ok() {
+		edits = append(edits, changeMemberships(name, changes.groups.Value(), false)...)
+	}
+
+	err := b.edit(edits...)
+	if err != nil {
+		return fmt.Errorf("failed to add user: %w", err)
+	}
+
+	if changes.createHome {
+		err = makeHome(b.path(home), changes.uid.Value(), changes.gid.Value())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *nativeBackend) modifyUser(name string, changes userChanges) error {
+	before, err := b.lookupPasswd(name)
+	if err != nil {
+		return fmt.Errorf("failed to lookup passwd entry: %w", err)
+	}
+
+	edits := []accountsEdit{
+		{path: passwdPath, edit: changeEntry(name, func(fields []string) error {
+			if len(fields) != 7 {
+				return fmt.Errorf("malformed passwd entry of %s", name)
+			}
+
+			setNumber(fields, 2, changes.uid)
+			setNumber(fields, 3, changes.gid)
+			setText(fields, 4, changes.comment)
+			setText(fields, 5, changes.home)
+			setText(fields, 6, changes.shell)
+			return nil
+		})},
+	}
+
+	if changes.password.Ok() || changes.locked.Ok() || changes.expired.Ok() || changes.aging() {
+		edits = append(edits, accountsEdit{path: shadowPath, edit: changeEntry(name, func(fields []string) error {
+			if len(fields) != 9 {
+				return fmt.Errorf("malformed shadow entry of %s", name)
+			}
+
+			changeShadowFields(fields, changes)
+			return nil
+		})})
+	}
+
+	if changes.groups.Ok() {
+		edits = append(edits, changeMemberships(name, changes.groups.Value(), changes.addGroups)...)
+	}
+
+	err = b.edit(edits...)
+	if err != nil {
+		return fmt.Errorf("failed to modify user: %w", err)
+	}
+
+	after, err := b.lookupPasswd(name)
+	if err != nil {
+		return fmt.Errorf("failed to lookup passwd entry: %w", err)
+	}
+
+	if changes.home.Ok() && changes.moveHome && before.home != after.home {
+		err = os.Rename(b.path(before.home), b.path(after.home))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to move home: %w", err)
+		}
+	}
+
+	if changes.createHome {
+		err = makeHome(b.path(after.home), after.uid, after.gid)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *nativeBackend) deleteUser(name string, removeHome bool) error {
+	entries, err := b.listPasswd()
+	if err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+
+	i := slices.IndexFunc(entries, func(entry passwdEntry) bool { return entry.name == name })
+	if i == -1 {
+		return fmt.Errorf("failed to lookup passwd entry: %w", user.UnknownUserError(name))
+	}
+
+	passwd := entries[i]
+	others := slices.Delete(slices.Clone(entries), i, i+1)
+
+	edits := []accountsEdit{
+		{path: passwdPath, edit: removeEntry(name)},
+		{path: shadowPath, edit: removeEntry(name)},
+	}
+	edits = append(edits, changeMemberships(name, []string{}, false)...)
+
+	// NOTE: The same as userdel, the user's private group (the primary group
+	// named after the user) is removed along with the user, unless it is the
+	// primary group of another user.
+	group, err := b.lookupGroup(name)
+	switch {
+	case errors.Is(err, user.UnknownGroupError(name)):
+	case err != nil:
+		return fmt.Errorf("failed to lookup group: %w", err)
+	case group.gid != passwd.gid:
+		logger.Global().Warn("keeping group named after user that is not its primary group", "name", name)
+	case slices.ContainsFunc(others, func(other passwdEntry) bool { return other.gid == group.gid }):
+		logger.Global().Warn("keeping user's group that is the primary group of another user", "name", name)
+	default:
+		edits = append(edits,
+			accountsEdit{path: groupPath, edit: removeEntry(name)},
+			accountsEdit{path: gshadowPath, edit: removeEntry(name), optional: true},
+		)
+	}
+
+	err = b.edit(edits...)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	if removeHome {
+		for _, path := range []string{passwd.home, filepath.Join("/var/mail", name)} {
+			reason, err := b.unremovable(path, passwd.uid, others)
+			if err != nil {
+				return err
+			}
+
+			if reason != "" {
+				logger.Global().Warn("skipping removal of user's files", "name", name, "path", path, "reason", reason)
+				continue
+			}
+
+			err = os.RemoveAll(b.path(path))
+			if err != nil {
+				return fmt.Errorf("failed to remove user's files: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// unremovable tells why the user's path (the home or the mail spool) can't be
+// removed, or returns an empty string if it can. Only a path owned by the user
+// and not shared with the other users is removed, so a home set to "/" or
+// "/home" (or to another user's home) can't take the system along.
+func (b *nativeBackend) unremovable(path string, uid int, others []passwdEntry) (string, error) {
+	path = filepath.Clean(path)
+
+	if !filepath.IsAbs(path) || strings.Count(path, "/") < 2 {
+		return "top-level directory", nil
+	}
+
+	for _, other := range others {
+		home := filepath.Clean(other.home)
+		if home == path || strings.HasPrefix(home, path+"/") {
+			return "shared with " + other.name, nil
+		}
+	}
+
+	stat, err := os.Lstat(b.path(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return "does not exist", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to stat user's files: %w", err)
+	}
+
+	if stat.Mode()&os.ModeSymlink != 0 {
+		return "symlink", nil
+	}
+
+	if owner, _ := fileOwnership(stat); owner != uid {
+		return fmt.Sprintf("owned by uid %d", owner), nil
+	}
+
+	return "", nil
+}
+
+func (b *nativeBackend) addGroup(name string, changes groupChanges) error {
+	if !changes.gid.Ok() {
+		return fmt.Errorf("failed to add group: gid is required")
+	}
+
+	members := strings.Join(changes.members.Value(), ",")
+
+	err := b.edit(
+		accountsEdit{
+			path: groupPath,
+			edit: addEntry([]string{name, "x", fmt.Sprint(changes.gid.Value()), members}),
+		},
+		accountsEdit{
+			path:     gshadowPath,
+			edit:     addEntry([]string{name, "!", "", members}),
+			optional: true,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add group: %w", err)
+	}
+
+	return nil
+}
+
+func (b *nativeBackend) modifyGroup(name string, changes groupChanges) error {
+	setMembers := func(fields []string) error {
+		if len(fields) != 4 {
+			return fmt.Errorf("malformed group entry of %s", name)
+		}
+
+		if changes.members.Ok() {
+			fields[3] = strings.Join(changes.members.Value(), ",")
+		}
+
+		if len(changes.addMembers) > 0 || len(changes.removeMembers) > 0 {
+			members := []string{}
+			if fields[3] != "" {
+				members = strings.Split(fields[3], ",")
+			}
+
+			for _, member := range changes.addMembers {
+				if !slices.Contains(members, member) {
+					members = append(members, member)
+				}
+			}
+
+			members = slices.DeleteFunc(members, func(member string) bool {
+				return slices.Contains(changes.removeMembers, member)
+			})
+
+			fields[3] = strings.Join(members, ",")
+		}
+
+		return nil
+	}
+
+	err := b.edit(
+		accountsEdit{path: groupPath, edit: changeEntry(name, func(fields []string) error {
+			err := setMembers(fields)
+			setNumber(fields, 2, changes.gid)
+			return err
+		})},
+		accountsEdit{path: gshadowPath, edit: changeEntry(name, setMembers), optional: true},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to modify group: %w", err)
+	}
+
+	return nil
+}
+
+// accountsEdit changes the lines of one of the account databases.
+type accountsEdit struct {
+	path     string
+	edit     func(lines []string) ([]string, error)
+	optional bool // the file is skipped if it does not exist (e.g., gshadow)
+}
+
+// edit applies the edits under the lock. Every file is written to a temporary
+// one first, which replaces the original only once all of them are written.
+func (b *nativeBackend) edit(edits ...accountsEdit) error {
+	accountsMu.Lock()
+	defer accountsMu.Unlock()
+
+	unlock, err := lockAccounts(b.path(accountsLockPath), accountsLockTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to lock account databases: %w", err)
+	}
+	defer unlock()
+
+	staged := make(map[string]string, len(edits))
+	defer func() {
+		for _, tmp := range staged {
+			os.Remove(tmp)
+		}
+	}()
+
+	for _, edit := range edits {
+		path := b.path(edit.path)
+
+		// NOTE: Multiple edits of the same file are applied on top of each other.
+		source := path
+		if tmp, ok := staged[path]; ok {
+			source = tmp
+		}
+
+		lines, err := readLines(source)
+		if errors.Is(err, os.ErrNotExist) && edit.optional {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		lines, err = edit.edit(lines)
+		if err != nil {
+			return fmt.Errorf("failed to edit %s: %w", path, err)
+		}
+
+		tmp, err := writeAccounts(path, lines)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+
+		// NOTE: The previous temporary file of the same path is replaced by
+		// the new one, which has all the edits applied.
+		if previous, ok := staged[path]; ok {
+			os.Remove(previous)
+		}
+		staged[path] = tmp
+	}
+
+	for path, tmp := range staged {
+		err = os.Rename(tmp, path)
+		if err != nil {
+			return fmt.Errorf("failed to replace %s: %w", path, err)
+		}
+
+		delete(staged, path)
+	}
+
+	return nil
+}
+
+// writeAccounts writes the lines to a new temporary file next to the original
+// one, with its mode and ownership, flushes it to the disk and returns its
+// path. The temporary file is created with a unique name and no permissions
+// for others, so neither a stale file (e.g., left by a crash) nor another
+// edit can expose or change the contents (e.g., of shadow).
+func writeAccounts(original string, lines []string) (string, error) {
+	stat, err := os.Stat(original)
+	if err != nil {
+		return "", err
+	}
+
+	content := ""
+	if len(lines) > 0 {
+		content = strings.Join(lines, "\n") + "\n"
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(original), "."+filepath.Base(original)+".align*")
+	if err != nil {
+		return "", err
+	}
+
+	uid, gid := fileOwnership(stat)
+	err = file.Chown(uid, gid)
+	if err == nil {
+		err = file.Chmod(permissionBits(stat.Mode()))
+	}
+	if err == nil {
+		_, err = file.WriteString(content)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+func addEntry(fields []string) func(lines []string) ([]string, error) {
+	return func(lines []string) ([]string, error) {
+		for _, field := range fields {
+			if strings.ContainsAny(field, ":\n") {
+				return nil, fmt.Errorf("invalid field: %q", field)
+			}
+		}
+
+		if entryIndex(lines, fields[0]) != -1 {
+			return nil, fmt.Errorf("entry already exists: %s", fields[0])
+		}
+
+		return append(lines, strings.Join(fields, ":")), nil
+	}
+}
+
+func changeEntry(name string, change func(fields []string) error) func(lines []string) ([]string, error) {
+	return func(lines []string) ([]string, error) {
+		i := entryIndex(lines, name)
+		if i == -1 {
+			return nil, fmt.Errorf("entry does not exist: %s", name)
+		}
+
+		fields := strings.Split(lines[i], ":")
+
+		err := change(fields)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, field := range fields {
+			if strings.ContainsAny(field, ":\n") {
+				return nil, fmt.Errorf("invalid field: %q", field)
+			}
+		}
+
+		lines = slices.Clone(lines)
+		lines[i] = strings.Join(fields, ":")
+		return lines, nil
+	}
+}
+
+func removeEntry(name string) func(lines []string) ([]string, error) {
+	return func(lines []string) ([]string, error) {
+		return slices.DeleteFunc(slices.Clone(lines), func(line string) bool {
+			return strings.HasPrefix(line, name+":")
+		}), nil
+	}
+}
+
+// changeMemberships makes the user a member of the groups (in both group and
+// gshadow) and, unless only adding, removes it from all the others.
+func changeMemberships(name string, groups []string, add bool) []accountsEdit {
+	edit := func(lines []string) ([]string, error) {
+		lines = slices.Clone(lines)
+		found := make([]string, 0, len(groups))
+
+		for i, line := range lines {
+			fields := strings.Split(line, ":")
+			if len(fields) != 4 || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			members := []string{}
+			if fields[3] != "" {
+				members = strings.Split(fields[3], ",")
+			}
+
+			member := slices.Contains(groups, fields[0])
+			if member {
+				found = append(found, fields[0])
+			}
+
+			switch {
+			case member && !slices.Contains(members, name):
+				members = append(members, name)
+			case !member && !add && slices.Contains(members, name):
+				members = slices.DeleteFunc(members, func(m string) bool { return m == name })
+			default:
+				continue
+			}
+
+			fields[3] = strings.Join(members, ",")
+			lines[i] = strings.Join(fields, ":")
+		}
+
+		for _, group := range groups {
+			if !slices.Contains(found, group) {
+				return nil, user.UnknownGroupError(group)
+			}
+		}
+
+		return lines, nil
+	}
+
+	return []accountsEdit{
+		{path: groupPath, edit: edit},
+		{path: gshadowPath, edit: edit, optional: true},
+	}
+}
+
+func changeShadowFields(fields []string, changes userChanges) {
+	setText(fields, 1, changes.password)
+
+	if changes.locked.Ok() {
+		password := strings.TrimPrefix(fields[1], "!")
+		if changes.locked.Value() {
+			password = "!" + password
+		}
+		fields[1] = password
+	}
+
+	if changes.expired.Ok() {
+		fields[7] = ""
+		if changes.expired.Value() {
+			fields[7] = "1"
+		}
+	}
+
+	setNumber(fields, 3, changes.minAge)
+	setNumber(fields, 4, changes.maxAge)
+	setNumber(fields, 5, changes.warnDays)
+	setNumber(fields, 7, changes.expiry)
+}
+
+func setText(fields []string, i int, value types.Optional[string]) {
+	if value.Ok() {
+		fields[i] = value.Value()
+	}
+}
+
+func setNumber(fields []string, i int, value types.Optional[int]) {
+	if value.Ok() {
+		fields[i] = fmt.Sprint(value.Value())
+	}
+}
+
+func entryIndex(lines []string, name string) int {
+	return slices.IndexFunc(lines, func(line string) bool {
+		return strings.HasPrefix(line, name+":")
+	})
+}
+
+func today() int {
+	return int(time.Now().Unix() / secondsPerDay)
+}
@@ -0,0 +1,382 @@
+package resources
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scherepiuk/align/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func testAccountsRoot(t *testing.T) string {
+	root := t.TempDir()
+
+	files := map[string]string{
+		passwdPath:  "root:x:0:0:root:/root:/bin/bash\n",
+		shadowPath:  "root:*:19000:0:99999:7:::\n",
+		groupPath:   "root:x:0:\nwheel:x:10:first\nusers:x:100:\n",
+		gshadowPath: "root:*::\nwheel:!::first\nusers:!::\n",
+	}
+
+	err := os.MkdirAll(filepath.Join(root, "etc"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, content := range files {
+		err := os.WriteFile(filepath.Join(root, path), []byte(content), 0o640)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return root
+}
+
+func assertAccountsFile(t *testing.T, root, path, expected string) {
+	actual, err := os.ReadFile(filepath.Join(root, path))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, string(actual))
+
+	tmps, err := filepath.Glob(filepath.Join(root, filepath.Dir(path), ".*.align*"))
+	assert.NoError(t, err)
+	assert.Empty(t, tmps)
+}
+
+func TestNativeBackendIntegration(t *testing.T) {
+	t.Run("user is created, modified and removed", func(t *testing.T) {
+		root := testAccountsRoot(t)
+		backend := NewNativeBackend(root)
+
+		user := NewUser(
+			"testing",
+			WithUid(42069),
+			WithGid(1000),
+			WithGroups("wheel"),
+			WithCreateHome(),
+			WithComment("Testing User"),
+			WithPasswordHash("$6$salt$hash"),
+			WithLocked(true),
+			WithUserBackend(backend),
+		)
+		expected := []Correction{user.create, user.changeUid, user.changeGid, user.setGroups}
+
+		actual, err := user.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		err = user.create()
+		assert.NoError(t, err)
+
+		corrections, err := user.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		assertAccountsFile(t, root, passwdPath, "root:x:0:0:root:/root:/bin/bash\n"+
+			"testing:x:42069:1000:Testing User:/home/testing:/bin/sh\n")
+		assertAccountsFile(t, root, groupPath, "root:x:0:\nwheel:x:10:first,testing\nusers:x:100:\n")
+		assertAccountsFile(t, root, gshadowPath, "root:*::\nwheel:!::first,testing\nusers:!::\n")
+
+		shadow, err := backend.lookupShadow("testing")
+		assert.NoError(t, err)
+		assert.Equal(t, "!$6$salt$hash", shadow.password)
+
+		stat, err := os.Stat(filepath.Join(root, "home/testing"))
+		assert.NoError(t, err)
+		assert.Equal(t, defaultHomeMode, permissionBits(stat.Mode()))
+
+		user = NewUser(
+			"testing",
+			WithUid(42070),
+			WithGid(1000),
+			WithGroups("users"),
+			WithMembership(MembershipExact),
+			WithShell("/bin/bash"),
+			WithLocked(false),
+			WithExpired(true),
+			WithUserBackend(backend),
+		)
+		expected = []Correction{user.changeUid, user.setGroups, user.changeShell, user.changeLocked, user.changeExpired}
+
+		actual, err = user.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		for _, correction := range actual {
+			assert.NoError(t, correction())
+		}
+
+		corrections, err = user.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		assertAccountsFile(t, root, groupPath, "root:x:0:\nwheel:x:10:first\nusers:x:100:testing\n")
+
+		// NOTE: Unlike usermod, the native backend leaves the ownership of the
+		// files alone, and only the files owned by the user are removed.
+		assert.NoError(t, os.Chown(filepath.Join(root, "home/testing"), 42070, 1000))
+
+		user = NewUser("testing", WithUid(42070), WithGid(1000), WithUserEnsure(EnsureAbsent), WithRemoveHome(), WithUserBackend(backend))
+
+		actual, err = user.Check()
+		assertCorrections(t, []Correction{user.remove}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		err = user.remove()
+		assert.NoError(t, err)
+
+		assertAccountsFile(t, root, passwdPath, "root:x:0:0:root:/root:/bin/bash\n")
+		assertAccountsFile(t, root, shadowPath, "root:*:19000:0:99999:7:::\n")
+		assertAccountsFile(t, root, groupPath, "root:x:0:\nwheel:x:10:first\nusers:x:100:\n")
+
+		_, err = os.Stat(filepath.Join(root, "home/testing"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("user's home is kept unless owned by the user alone", func(t *testing.T) {
+		root := testAccountsRoot(t)
+		backend := NewNativeBackend(root)
+
+		add := func(name string, uid int, home string) {
+			assert.NoError(t, os.MkdirAll(filepath.Join(root, home), 0o755))
+			assert.NoError(t, backend.addUser(name, userChanges{
+				uid:  types.NewOptional(uid),
+				gid:  types.NewOptional(1000),
+				home: types.NewOptional(home),
+			}))
+		}
+
+		add("top", 42069, "/home")
+		add("outer", 42070, "/home/outer")
+		add("inner", 42071, "/home/outer/inner")
+		add("foreign", 42072, "/srv/foreign")
+
+		assert.NoError(t, os.Chown(filepath.Join(root, "home/outer"), 42070, 1000))
+
+		for _, name := range []string{"top", "outer", "foreign"} {
+			assert.NoError(t, backend.deleteUser(name, true))
+		}
+
+		assert.DirExists(t, filepath.Join(root, "home/outer/inner"))
+		assert.DirExists(t, filepath.Join(root, "srv/foreign"))
+
+		add("outer", 42070, "/home/outer")
+		assert.NoError(t, backend.deleteUser("inner", false))
+		assert.NoError(t, backend.deleteUser("outer", true))
+
+		assert.NoDirExists(t, filepath.Join(root, "home/outer"))
+	})
+
+	t.Run("concurrent edits are serialized", func(t *testing.T) {
+		root := testAccountsRoot(t)
+
+		var wg sync.WaitGroup
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// NOTE: Every edit has a backend of its own, the same as the
+				// resources aligned at the same time.
+				backend := NewNativeBackend(root)
+				name := fmt.Sprintf("testing%d", i)
+				assert.NoError(t, backend.addUser(name, userChanges{uid: types.NewOptional(42069 + i), gid: types.NewOptional(1000)}))
+			}()
+		}
+		wg.Wait()
+
+		entries, err := NewNativeBackend(root).listPasswd()
+		assert.NoError(t, err)
+		assert.Len(t, entries, 9)
+
+		original, err := os.Stat(filepath.Join(root, shadowPath))
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o640), permissionBits(original.Mode()))
+
+		tmps, err := filepath.Glob(filepath.Join(root, "etc", ".*.align*"))
+		assert.NoError(t, err)
+		assert.Empty(t, tmps)
+	})
+
+	t.Run("user's private group is removed with the user", func(t *testing.T) {
+		root := testAccountsRoot(t)
+		backend := NewNativeBackend(root)
+
+		assert.NoError(t, backend.addGroup("private", groupChanges{gid: types.NewOptional(2000)}))
+		assert.NoError(t, backend.addGroup("shared", groupChanges{gid: types.NewOptional(2001)}))
+		assert.NoError(t, backend.addUser("private", userChanges{uid: types.NewOptional(42069), gid: types.NewOptional(2000)}))
+		assert.NoError(t, backend.addUser("shared", userChanges{uid: types.NewOptional(42070), gid: types.NewOptional(2001)}))
+		assert.NoError(t, backend.addUser("other", userChanges{uid: types.NewOptional(42071), gid: types.NewOptional(2001)}))
+
+		assert.NoError(t, backend.deleteUser("private", false))
+		assert.NoError(t, backend.deleteUser("shared", false))
+
+		assertAccountsFile(t, root, groupPath, "root:x:0:\nwheel:x:10:first\nusers:x:100:\nshared:x:2001:\n")
+		assertAccountsFile(t, root, gshadowPath, "root:*::\nwheel:!::first\nusers:!::\nshared:!::\n")
+	})
+
+	t.Run("user's groups are added or replaced", func(t *testing.T) {
+		root := testAccountsRoot(t)
+		backend := NewNativeBackend(root)
+
+		user := NewUser("testing", WithUid(42069), WithGid(1000), WithGroups("wheel"), WithUserBackend(backend))
+		assert.NoError(t, user.create())
+
+		user = NewUser("testing", WithUid(42069), WithGid(1000), WithGroups("users"), WithUserBackend(backend))
+
+		actual, err := user.Check()
+		assertCorrections(t, []Correction{user.setGroups}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, user.setGroups())
+		assertAccountsFile(t, root, groupPath, "root:x:0:\nwheel:x:10:first,testing\nusers:x:100:testing\n")
+
+		corrections, err := user.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		user = NewUser(
+			"testing",
+			WithUid(42069),
+			WithGid(1000),
+			WithGroups("users"),
+			WithMembership(MembershipExact),
+			WithUserBackend(backend),
+		)
+
+		missing, extra, err := user.groupsDrift(passwdEntry{name: "testing", gid: 1000})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{}, missing)
+		assert.Equal(t, []string{"wheel"}, extra)
+
+		actual, err = user.Check()
+		assertCorrections(t, []Correction{user.setGroups}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, user.setGroups())
+		assertAccountsFile(t, root, groupPath, "root:x:0:\nwheel:x:10:first\nusers:x:100:testing\n")
+	})
+
+	t.Run("user with allocated ids gets private group", func(t *testing.T) {
+		root := testAccountsRoot(t)
+		backend := NewNativeBackend(root)
+
+		user := NewUser("testing", WithUidRange(2000, 2999), WithUserBackend(backend))
+		assert.NoError(t, user.create())
+
+		corrections, err := user.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		passwd, err := backend.lookupPasswd("testing")
+		assert.NoError(t, err)
+
+		group, err := backend.lookupGroup("testing")
+		assert.NoError(t, err)
+
+		assert.GreaterOrEqual(t, passwd.uid, 2000)
+		assert.LessOrEqual(t, passwd.uid, 2999)
+		assert.Equal(t, passwd.uid, group.gid)
+		assert.Equal(t, passwd.gid, group.gid)
+
+		user = NewUser("testing", WithPrimaryGroup("users"), WithUserBackend(backend))
+
+		actual, err := user.Check()
+		assertCorrections(t, []Correction{user.changeGid}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, user.changeGid())
+
+		passwd, err = backend.lookupPasswd("testing")
+		assert.NoError(t, err)
+		assert.Equal(t, 100, passwd.gid)
+	})
+
+	t.Run("group is created and modified", func(t *testing.T) {
+		root := testAccountsRoot(t)
+		backend := NewNativeBackend(root)
+
+		group := NewGroup("docker", WithGroupGid(999), WithMembers("first", "second"), WithGroupBackend(backend))
+
+		actual, err := group.Check()
+		assertCorrections(t, []Correction{group.create, group.setMembers}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		for _, correction := range actual {
+			assert.NoError(t, correction())
+		}
+
+		group = NewGroup("docker", WithGroupGid(998), WithMembers("second"), WithGroupBackend(backend))
+
+		actual, err = group.Check()
+		assertCorrections(t, []Correction{group.changeGid, group.setMembers}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		for _, correction := range actual {
+			assert.NoError(t, correction())
+		}
+
+		corrections, err := group.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		assertAccountsFile(t, root, groupPath, "root:x:0:\nwheel:x:10:first\nusers:x:100:\ndocker:x:998:second\n")
+		assertAccountsFile(t, root, gshadowPath, "root:*::\nwheel:!::first\nusers:!::\ndocker:!::second\n")
+	})
+
+	t.Run("user is added to a missing group", func(t *testing.T) {
+		root := testAccountsRoot(t)
+		user := NewUser("testing", WithUid(42069), WithGid(1000), WithGroups("missing"), WithUserBackend(NewNativeBackend(root)))
+
+		err := user.create()
+		assert.ErrorContains(t, err, "unknown group missing")
+
+		assertAccountsFile(t, root, passwdPath, "root:x:0:0:root:/root:/bin/bash\n")
+	})
+
+	t.Run("user's password aging is changed", func(t *testing.T) {
+		root := testAccountsRoot(t)
+		backend := NewNativeBackend(root)
+
+		user := NewUser("testing", WithUid(42069), WithGid(1000), WithPasswordMaxAge(90), WithUserBackend(backend))
+		assert.NoError(t, user.create())
+
+		shadow, err := backend.lookupShadow("testing")
+		assert.NoError(t, err)
+		assert.Equal(t, types.NewOptional(90), shadow.maxAge)
+
+		user = NewUser(
+			"testing",
+			WithUid(42069),
+			WithGid(1000),
+			WithPasswordMaxAge(60),
+			WithPasswordMinAge(1),
+			WithPasswordWarnDays(14),
+			WithAccountExpiry(time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)),
+			WithUserBackend(backend),
+		)
+		expected := []Correction{user.changeMaxAge, user.changeMinAge, user.changeWarnDays, user.changeExpiry}
+
+		actual, err := user.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		for _, correction := range actual {
+			assert.NoError(t, correction())
+		}
+
+		corrections, err := user.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		shadow, err = backend.lookupShadow("testing")
+		assert.NoError(t, err)
+		assert.Equal(t, types.NewOptional(1), shadow.minAge)
+		assert.Equal(t, types.NewOptional(60), shadow.maxAge)
+		assert.Equal(t, types.NewOptional(14), shadow.warnDays)
+		assert.Equal(t, types.NewOptional(21915), shadow.expire)
+	})
+}
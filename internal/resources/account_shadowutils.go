@@ -0,0 +1,350 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/scherepiuk/align/internal/logger"
+)
+
+// shadowUtilsBackend changes the accounts with useradd, usermod, groupadd and
+// friends. The accounts are looked up with getent, so the ones provided by NSS
+// (e.g., by LDAP through SSSD) are found as well, while the listings only
+// cover the local databases, since remote ones are often not enumerable and
+// are not for align to manage.
+type shadowUtilsBackend struct {
+	accountFiles
+	runner CommandRunner
+}
+
+func NewShadowUtilsBackend(opts ...ShadowUtilsOption) AccountBackend {
+	backend := &shadowUtilsBackend{accountFiles: accountFiles{root: "/"}, runner: NewExecRunner()}
+
+	for _, opt := range opts {
+		opt(backend)
+	}
+
+	return backend
+}
+
+type ShadowUtilsOption func(backend *shadowUtilsBackend)
+
+// WithShadowUtilsRunner changes how the commands of the backend are run, they
+// are executed on the host by default.
+func WithShadowUtilsRunner(runner CommandRunner) ShadowUtilsOption {
+	return func(backend *shadowUtilsBackend) {
+		logger.Global().Info("specifying shadow-utils runner", "runner", fmt.Sprintf("%T", runner))
+		backend.runner = runner
+	}
+}
+
+func (b *shadowUtilsBackend) lookupPasswd(name string) (passwdEntry, error) {
+	entry, ok, err := getent(b.runner, "passwd", name, parsePasswdEntry)
+	if err == nil && (!ok || entry.name != name) {
+		err = user.UnknownUserError(name)
+	}
+
+	return entry, err
+}
+
+func (b *shadowUtilsBackend) lookupShadow(name string) (shadowEntry, error) {
+	entry, ok, err := getent(b.runner, "shadow", name, parseShadowEntry)
+	if err == nil && (!ok || entry.name != name) {
+		err = user.UnknownUserError(name)
+	}
+
+	return entry, err
+}
+
+func (b *shadowUtilsBackend) lookupGroup(name string) (groupEntry, error) {
+	entry, ok, err := getent(b.runner, "group", name, parseGroupEntry)
+	if err == nil && (!ok || entry.name != name) {
+		err = user.UnknownGroupError(name)
+	}
+
+	return entry, err
+}
+
+func (b *shadowUtilsBackend) addUser(name string, changes userChanges) error {
+	args := userModArgs(changes)
+
+	if changes.createHome {
+		args = append(args, "-m")
+	} else {
+		args = append(args, "-M")
+	}
+
+	if changes.system {
+		args = append(args, "-r")
+	}
+
+	err := b.run("useradd", append(args, name)...)
+	if err != nil {
+		return fmt.Errorf("failed to add user: %w", err)
+	}
+
+	err = b.changeLocked(name, changes)
+	if err != nil {
+		return err
+	}
+
+	return b.changeAging(name, changes)
+}
+
+func (b *shadowUtilsBackend) modifyUser(name string, changes userChanges) error {
+	args := userModArgs(changes)
+
+	if changes.home.Ok() && changes.moveHome {
+		args = append(args, "-m")
+	}
+
+	if len(args) > 0 {
+		err := b.run("usermod", append(args, name)...)
+		if err != nil {
+			return fmt.Errorf("failed to modify user: %w", err)
+		}
+	}
+
+	err := b.changeLocked(name, changes)
+	if err != nil {
+		return err
+	}
+
+	err = b.changeAging(name, changes)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: usermod can't create the home of an existing user.
+	if changes.createHome {
+		passwd, err := b.lookupPasswd(name)
+		if err != nil {
+			return fmt.Errorf("failed to lookup passwd entry: %w", err)
+		}
+
+		err = makeHome(passwd.home, passwd.uid, passwd.gid)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *shadowUtilsBackend) deleteUser(name string, removeHome bool) error {
+	args := []string{name}
+	if removeHome {
+		args = []string{"-r", name}
+	}
+
+	err := b.run("userdel", args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return nil
+}
+
+func (b *shadowUtilsBackend) addGroup(name string, changes groupChanges) error {
+	args := make([]string, 0)
+	if changes.gid.Ok() {
+		args = append(args, "-g", fmt.Sprint(changes.gid.Value()))
+	}
+
+	if changes.system {
+		args = append(args, "-r")
+	}
+
+	err := b.run("groupadd", append(args, name)...)
+	if err != nil {
+		return fmt.Errorf("failed to add group: %w", err)
+	}
+
+	return b.setMembers(name, changes)
+}
+
+func (b *shadowUtilsBackend) modifyGroup(name string, changes groupChanges) error {
+	if changes.gid.Ok() {
+		err := b.run("groupmod", "-g", fmt.Sprint(changes.gid.Value()), name)
+		if err != nil {
+			return fmt.Errorf("failed to modify group: %w", err)
+		}
+	}
+
+	return b.setMembers(name, changes)
+}
+
+// changeLocked locks the user separately, usermod refuses to combine -L and
+// -U with -p.
+func (b *shadowUtilsBackend) changeLocked(name string, changes userChanges) error {
+	if !changes.locked.Ok() {
+		return nil
+	}
+
+	flag := "-U"
+	if changes.locked.Value() {
+		flag = "-L"
+	}
+
+	err := b.run("usermod", flag, name)
+	if err != nil {
+		return fmt.Errorf("failed to change user's lock state: %w", err)
+	}
+
+	return nil
+}
+
+// changeAging sets the password aging fields with chage, which (unlike
+// usermod) covers all of them.
+func (b *shadowUtilsBackend) changeAging(name string, changes userChanges) error {
+	if !changes.aging() {
+		return nil
+	}
+
+	args := make([]string, 0)
+
+	if changes.maxAge.Ok() {
+		args = append(args, "-M", fmt.Sprint(changes.maxAge.Value()))
+	}
+
+	if changes.minAge.Ok() {
+		args = append(args, "-m", fmt.Sprint(changes.minAge.Value()))
+	}
+
+	if changes.warnDays.Ok() {
+		args = append(args, "-W", fmt.Sprint(changes.warnDays.Value()))
+	}
+
+	if changes.expiry.Ok() {
+		date := time.Unix(int64(changes.expiry.Value())*secondsPerDay, 0).UTC()
+		args = append(args, "-E", date.Format(time.DateOnly))
+	}
+
+	err := b.run("chage", append(args, name)...)
+	if err != nil {
+		return fmt.Errorf("failed to change user's password aging: %w", err)
+	}
+
+	return nil
+}
+
+// setMembers replaces the members with gpasswd -M, or adds and removes them
+// one by one, which leaves the other members alone.
+func (b *shadowUtilsBackend) setMembers(name string, changes groupChanges) error {
+	if changes.members.Ok() {
+		err := b.run("gpasswd", "-M", strings.Join(changes.members.Value(), ","), name)
+		if err != nil {
+			return fmt.Errorf("failed to set group's members: %w", err)
+		}
+	}
+
+	for _, member := range changes.addMembers {
+		err := b.run("gpasswd", "-a", member, name)
+		if err != nil {
+			return fmt.Errorf("failed to add group's member: %w", err)
+		}
+	}
+
+	for _, member := range changes.removeMembers {
+		err := b.run("gpasswd", "-d", member, name)
+		if err != nil {
+			return fmt.Errorf("failed to remove group's member: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// userModArgs returns the arguments shared by useradd and usermod.
+func userModArgs(changes userChanges) []string {
+	args := make([]string, 0)
+
+	if changes.uid.Ok() {
+		args = append(args, "-u", fmt.Sprint(changes.uid.Value()))
+	}
+
+	if changes.gid.Ok() {
+		args = append(args, "-g", fmt.Sprint(changes.gid.Value()))
+	}
+
+	if changes.groups.Ok() {
+		if changes.addGroups {
+			args = append(args, "-a")
+		}
+		args = append(args, "-G", strings.Join(changes.groups.Value(), ","))
+	}
+
+	if changes.home.Ok() {
+		args = append(args, "-d", changes.home.Value())
+	}
+
+	if changes.shell.Ok() {
+		args = append(args, "-s", changes.shell.Value())
+	}
+
+	if changes.comment.Ok() {
+		args = append(args, "-c", changes.comment.Value())
+	}
+
+	if changes.password.Ok() {
+		args = append(args, "-p", changes.password.Value())
+	}
+
+	// NOTE: Day 1 since the epoch is always in the past, empty value removes
+	// the expiration date altogether.
+	if changes.expired.Ok() && !changes.expiry.Ok() {
+		expire := ""
+		if changes.expired.Value() {
+			expire = "1"
+		}
+		args = append(args, "-e", expire)
+	}
+
+	return args
+}
+
+// getentNotFound is the exit code of getent when the key is not found.
+const getentNotFound = 2
+
+// getent looks the key up in the NSS database, and tells whether it's found.
+// The entry has to be matched against the name by the caller, since getent
+// also looks numeric keys up by id.
+func getent[T any](runner CommandRunner, database, key string, parse func(line string) (T, error)) (T, bool, error) {
+	var entry T
+
+	command := Command{Name: "getent", Args: []string{database, "--", key}}
+
+	result, err := runner.Run(context.Background(), command)
+	if err != nil {
+		return entry, false, fmt.Errorf("failed to lookup %s entry: %w", database, err)
+	}
+
+	if result.ExitCode == getentNotFound {
+		return entry, false, nil
+	}
+
+	if result.ExitCode != 0 {
+		return entry, false, fmt.Errorf(
+			"failed to lookup %s entry: %s: exit status %d: %s",
+			database, command, result.ExitCode, result.output(),
+		)
+	}
+
+	line, _, _ := strings.Cut(string(result.Stdout), "\n")
+
+	entry, err = parse(line)
+	if err != nil {
+		return entry, false, err
+	}
+
+	return entry, true, nil
+}
+
+// run runs the command, the error includes what it printed.
+func (b *shadowUtilsBackend) run(name string, args ...string) error {
+	_, err := runChecked(context.Background(), b.runner, Command{Name: name, Args: args})
+	return err
+}
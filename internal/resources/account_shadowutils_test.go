@@ -0,0 +1,76 @@
+package resources
+
+import (
+	"os/user"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShadowUtilsBackendLookupIntegration(t *testing.T) {
+	backend := NewShadowUtilsBackend()
+
+	passwd, err := backend.lookupPasswd("root")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, passwd.uid)
+
+	group, err := backend.lookupGroup("root")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, group.gid)
+
+	_, err = backend.lookupPasswd("0")
+	assert.ErrorIs(t, err, user.UnknownUserError("0"))
+
+	_, err = backend.lookupPasswd("align-missing")
+	assert.ErrorIs(t, err, user.UnknownUserError("align-missing"))
+
+	_, err = backend.lookupGroup("align-missing")
+	assert.ErrorIs(t, err, user.UnknownGroupError("align-missing"))
+}
+
+func TestShadowUtilsBackendUnit(t *testing.T) {
+	t.Run("commands are run with the runner", func(t *testing.T) {
+		runner := &fakeRunner{answer: func(command Command) CommandResult {
+			switch command.String() {
+			case "getent passwd -- align":
+				return CommandResult{Stdout: []byte("align:x:1000:1000::/home/align:/bin/sh\n")}
+			case "getent group -- align":
+				return CommandResult{ExitCode: getentNotFound}
+			case "userdel -r align":
+				return CommandResult{Stderr: []byte("userdel: user align is currently used by process 1"), ExitCode: 8}
+			default:
+				return CommandResult{}
+			}
+		}}
+		backend := NewShadowUtilsBackend(WithShadowUtilsRunner(runner))
+
+		passwd, err := backend.lookupPasswd("align")
+		assert.NoError(t, err)
+		assert.Equal(t, 1000, passwd.uid)
+
+		_, err = backend.lookupGroup("align")
+		assert.ErrorIs(t, err, user.UnknownGroupError("align"))
+
+		assert.NoError(t, backend.addGroup("align", groupChanges{}))
+
+		err = backend.deleteUser("align", true)
+		assert.ErrorContains(t, err, "userdel -r align: exit status 8: userdel: user align is currently used by process 1")
+
+		assert.Equal(t, []string{
+			"getent passwd -- align",
+			"getent group -- align",
+			"groupadd align",
+			"userdel -r align",
+		}, runner.commands)
+	})
+
+	t.Run("getent fails", func(t *testing.T) {
+		runner := &fakeRunner{answer: func(command Command) CommandResult {
+			return CommandResult{Stderr: []byte("Unknown database: shadow"), ExitCode: 1}
+		}}
+		backend := NewShadowUtilsBackend(WithShadowUtilsRunner(runner))
+
+		_, err := backend.lookupShadow("align")
+		assert.ErrorContains(t, err, "failed to lookup shadow entry: getent shadow -- align: exit status 1: Unknown database: shadow")
+	})
+}
@@ -0,0 +1,331 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/scherepiuk/align/internal/logger"
+)
+
+// accountsWatchers are shared by all the users whose backends operate on the
+// same root, so there is a single fsnotify watcher no matter how many users
+// are managed.
+var accountsWatchers = struct {
+	sync.Mutex
+	byRoot map[string]*accountsWatcher
+}{byRoot: make(map[string]*accountsWatcher)}
+
+// accountsWatcher watches /etc for the account databases being changed, and
+// notifies only the users whose entries have changed.
+type accountsWatcher struct {
+	backend AccountBackend
+	cancel  context.CancelFunc
+
+	mu          sync.Mutex
+	subscribers map[string][]*accountsSubscription
+	snapshot    accountsSnapshot
+}
+
+type accountsSubscription struct {
+	changes chan struct{} // coalesced, at most one pending notification
+	errs    chan error
+}
+
+// anyAccount subscribes to the changes of all the users and groups.
+const anyAccount = ""
+
+// groupAccount subscribes to the changes of the group. It can't be mistaken
+// for a user, whose names can't contain colons.
+func groupAccount(name string) string {
+	return "group:" + name
+}
+
+// subscribeAccounts returns the subscription to the changes of the user (or
+// of a group, see groupAccount, or of any of them, see anyAccount), and the
+// function cancelling it. The watcher
+// is started by the first subscriber and stopped when the last one is gone.
+func subscribeAccounts(backend AccountBackend, name string) (*accountsSubscription, func(), error) {
+	accountsWatchers.Lock()
+	defer accountsWatchers.Unlock()
+
+	root := backend.path("/")
+
+	watcher, ok := accountsWatchers.byRoot[root]
+	if !ok {
+		var err error
+		watcher, err = startAccountsWatcher(backend)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		accountsWatchers.byRoot[root] = watcher
+	}
+
+	subscription := &accountsSubscription{
+		changes: make(chan struct{}, 1),
+		errs:    make(chan error, 1),
+	}
+
+	watcher.mu.Lock()
+	watcher.subscribers[name] = append(watcher.subscribers[name], subscription)
+	watcher.mu.Unlock()
+
+	unsubscribe := func() {
+		accountsWatchers.Lock()
+		defer accountsWatchers.Unlock()
+
+		watcher.mu.Lock()
+		defer watcher.mu.Unlock()
+
+		watcher.subscribers[name] = slices.DeleteFunc(
+			watcher.subscribers[name],
+			func(s *accountsSubscription) bool { return s == subscription },
+		)
+		if len(watcher.subscribers[name]) == 0 {
+			delete(watcher.subscribers, name)
+		}
+
+		if len(watcher.subscribers) == 0 && accountsWatchers.byRoot[root] == watcher {
+			watcher.cancel()
+			delete(accountsWatchers.byRoot, root)
+		}
+	}
+
+	return subscription, unsubscribe, nil
+}
+
+func startAccountsWatcher(backend AccountBackend) (*accountsWatcher, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: The databases are replaced by renaming (both by shadow-utils and
+	// by the native backend), so the directory is watched, not the files.
+	err = fsWatcher.Add(backend.path(filepath.Dir(passwdPath)))
+	if err != nil {
+		fsWatcher.Close()
+		return nil, fmt.Errorf("failed to watch account databases: %w", err)
+	}
+
+	snapshot, err := takeAccountsSnapshot(backend)
+	if err != nil {
+		fsWatcher.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	watcher := &accountsWatcher{
+		backend:     backend,
+		cancel:      cancel,
+		subscribers: make(map[string][]*accountsSubscription),
+		snapshot:    snapshot,
+	}
+
+	go watcher.run(ctx, fsWatcher)
+
+	return watcher, nil
+}
+
+func (w *accountsWatcher) run(ctx context.Context, fsWatcher *fsnotify.Watcher) {
+	defer fsWatcher.Close()
+
+	watched := []string{
+		w.backend.path(passwdPath),
+		w.backend.path(groupPath),
+		w.backend.path(shadowPath),
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case err := <-fsWatcher.Errors:
+			w.fail(err)
+			return
+
+		case event := <-fsWatcher.Events:
+			if !slices.Contains(watched, event.Name) {
+				continue
+			}
+
+			logger.Global().Debug("got fsnotify event", "event", event.String())
+
+			snapshot, err := takeAccountsSnapshot(w.backend)
+			if err != nil {
+				w.fail(err)
+				return
+			}
+
+			w.mu.Lock()
+			affected := w.snapshot.affected(snapshot)
+			w.snapshot = snapshot
+
+			if len(affected) > 0 {
+				affected = append(affected, anyAccount)
+			}
+
+			for _, name := range affected {
+				for _, subscription := range w.subscribers[name] {
+					select {
+					case subscription.changes <- struct{}{}:
+					default:
+					}
+				}
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// fail passes the error to all of the subscribers, the watcher is unusable
+// afterwards.
+func (w *accountsWatcher) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, subscriptions := range w.subscribers {
+		for _, subscription := range subscriptions {
+			select {
+			case subscription.errs <- err:
+			default:
+			}
+		}
+	}
+}
+
+// accountsSnapshot is the content of the account databases, keyed by names.
+type accountsSnapshot struct {
+	passwd map[string]passwdEntry
+	shadow map[string]shadowEntry
+	groups map[string]groupEntry
+}
+
+func takeAccountsSnapshot(backend AccountBackend) (accountsSnapshot, error) {
+	passwd, err := readSnapshotEntries(
+		backend.path(passwdPath),
+		parsePasswdEntry,
+		func(entry passwdEntry) string { return entry.name },
+	)
+	if err != nil {
+		return accountsSnapshot{}, err
+	}
+
+	shadow, err := readSnapshotEntries(
+		backend.path(shadowPath),
+		parseShadowEntry,
+		func(entry shadowEntry) string { return entry.name },
+	)
+	if err != nil {
+		return accountsSnapshot{}, err
+	}
+
+	groups, err := readSnapshotEntries(
+		backend.path(groupPath),
+		parseGroupEntry,
+		func(entry groupEntry) string { return entry.name },
+	)
+	if err != nil {
+		return accountsSnapshot{}, err
+	}
+
+	return accountsSnapshot{passwd: passwd, shadow: shadow, groups: groups}, nil
+}
+
+// readSnapshotEntries treats a missing or unreadable database as an empty one,
+// e.g., there may be no shadow passwords at all, or align may not be root.
+func readSnapshotEntries[T any](
+	path string,
+	parse func(line string) (T, error),
+	key func(entry T) string,
+) (map[string]T, error) {
+	entries, err := readEntries(path, parse)
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission) {
+		return map[string]T{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]T, len(entries))
+	for _, entry := range entries {
+		byKey[key(entry)] = entry
+	}
+
+	return byKey, nil
+}
+
+// affected returns the names of the users whose passwd or shadow entries have
+// changed, the changed groups (see groupAccount), and the users affected by
+// them: their members before and after the change, and the users having them
+// as primary groups.
+func (s accountsSnapshot) affected(next accountsSnapshot) []string {
+	affected := make([]string, 0)
+	add := func(names ...string) {
+		for _, name := range names {
+			if !slices.Contains(affected, name) {
+				affected = append(affected, name)
+			}
+		}
+	}
+
+	for _, name := range changedKeys(s.passwd, next.passwd, func(a, b passwdEntry) bool { return a == b }) {
+		add(name)
+	}
+
+	for _, name := range changedKeys(s.shadow, next.shadow, func(a, b shadowEntry) bool { return a == b }) {
+		add(name)
+	}
+
+	sameGroup := func(a, b groupEntry) bool {
+		return a.gid == b.gid && slices.Equal(a.members, b.members)
+	}
+
+	for _, name := range changedKeys(s.groups, next.groups, sameGroup) {
+		before, after := s.groups[name], next.groups[name]
+		add(groupAccount(name))
+		add(before.members...)
+		add(after.members...)
+
+		_, existed := s.groups[name]
+		_, exists := next.groups[name]
+
+		for _, passwd := range []map[string]passwdEntry{s.passwd, next.passwd} {
+			for _, entry := range passwd {
+				if (existed && entry.gid == before.gid) || (exists && entry.gid == after.gid) {
+					add(entry.name)
+				}
+			}
+		}
+	}
+
+	return affected
+}
+
+func changedKeys[T any](before, after map[string]T, equal func(a, b T) bool) []string {
+	changed := make([]string, 0)
+
+	for key, entry := range before {
+		other, ok := after[key]
+		if !ok || !equal(entry, other) {
+			changed = append(changed, key)
+		}
+	}
+
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,139 @@
+package resources
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/scherepiuk/align/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountsSnapshotAffectedUnit(t *testing.T) {
+	before := accountsSnapshot{
+		passwd: map[string]passwdEntry{
+			"first":  {name: "first", uid: 1000, gid: 1000, shell: "/bin/sh"},
+			"second": {name: "second", uid: 1001, gid: 100, shell: "/bin/sh"},
+			"third":  {name: "third", uid: 1002, gid: 1002, shell: "/bin/sh"},
+		},
+		shadow: map[string]shadowEntry{
+			"first": {name: "first", password: "!"},
+		},
+		groups: map[string]groupEntry{
+			"users": {name: "users", gid: 100, members: []string{}},
+			"wheel": {name: "wheel", gid: 10, members: []string{"third"}},
+		},
+	}
+
+	type testCase struct {
+		name     string
+		change   func(next *accountsSnapshot)
+		expected []string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "nothing has changed",
+			change:   func(next *accountsSnapshot) {},
+			expected: []string{},
+		},
+		{
+			name: "passwd entry has changed",
+			change: func(next *accountsSnapshot) {
+				next.passwd["first"] = passwdEntry{name: "first", uid: 1000, gid: 1000, shell: "/bin/bash"}
+			},
+			expected: []string{"first"},
+		},
+		{
+			name: "shadow entry has changed",
+			change: func(next *accountsSnapshot) {
+				next.shadow["first"] = shadowEntry{name: "first", password: "!", expire: types.NewOptional(1)}
+			},
+			expected: []string{"first"},
+		},
+		{
+			name: "passwd entry is added",
+			change: func(next *accountsSnapshot) {
+				next.passwd["fourth"] = passwdEntry{name: "fourth", uid: 1003, gid: 1003}
+			},
+			expected: []string{"fourth"},
+		},
+		{
+			name: "group members have changed",
+			change: func(next *accountsSnapshot) {
+				next.groups["wheel"] = groupEntry{name: "wheel", gid: 10, members: []string{"first"}}
+			},
+			expected: []string{"first", "group:wheel", "third"},
+		},
+		{
+			name: "group without members is added",
+			change: func(next *accountsSnapshot) {
+				next.groups["docker"] = groupEntry{name: "docker", gid: 998, members: []string{}}
+			},
+			expected: []string{"group:docker"},
+		},
+		{
+			name: "primary group is removed",
+			change: func(next *accountsSnapshot) {
+				delete(next.groups, "users")
+			},
+			expected: []string{"group:users", "second"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next := accountsSnapshot{
+				passwd: maps.Clone(before.passwd),
+				shadow: maps.Clone(before.shadow),
+				groups: maps.Clone(before.groups),
+			}
+			tc.change(&next)
+
+			actual := before.affected(next)
+			slices.Sort(actual)
+
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestSubscribeAccountsIntegration(t *testing.T) {
+	root := testAccountsRoot(t)
+	backend := NewNativeBackend(root)
+
+	for _, name := range []string{"first", "second"} {
+		err := backend.addUser(name, userChanges{uid: types.NewOptional(1000), gid: types.NewOptional(1000)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, unsubscribeFirst, err := subscribeAccounts(backend, "first")
+	assert.NoError(t, err)
+	defer unsubscribeFirst()
+
+	second, unsubscribeSecond, err := subscribeAccounts(NewNativeBackend(root), "second")
+	assert.NoError(t, err)
+	defer unsubscribeSecond()
+
+	accountsWatchers.Lock()
+	assert.Len(t, accountsWatchers.byRoot, 1)
+	accountsWatchers.Unlock()
+
+	err = backend.modifyUser("first", userChanges{shell: types.NewOptional("/bin/bash")})
+	assert.NoError(t, err)
+
+	select {
+	case <-first.changes:
+	case <-time.After(time.Second):
+		t.Fatal("first user has not been notified")
+	}
+
+	select {
+	case <-second.changes:
+		t.Fatal("second user has been notified")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,287 @@
+package resources
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	aclXattr   = "system.posix_acl_access"
+	aclVersion = 2
+	aclNoId    = 0xffffffff
+)
+
+// Tags of POSIX ACL entries, in the order the kernel expects them.
+const (
+	aclUserObj  uint16 = 0x01
+	aclUser     uint16 = 0x02
+	aclGroupObj uint16 = 0x04
+	aclGroup    uint16 = 0x08
+	aclMask     uint16 = 0x10
+	aclOther    uint16 = 0x20
+)
+
+type aclEntry struct {
+	tag  uint16
+	id   uint32
+	perm uint16
+}
+
+func (e aclEntry) String() string {
+	var tag string
+	switch e.tag {
+	case aclUserObj, aclUser:
+		tag = "user"
+	case aclGroupObj, aclGroup:
+		tag = "group"
+	case aclMask:
+		tag = "mask"
+	case aclOther:
+		tag = "other"
+	}
+
+	id := ""
+	if e.id != aclNoId {
+		id = fmt.Sprint(e.id)
+	}
+
+	perm := []byte("---")
+	for i, c := range []byte("rwx") {
+		if e.perm&(4>>i) != 0 {
+			perm[i] = c
+		}
+	}
+
+	return fmt.Sprintf("%s:%s:%s", tag, id, perm)
+}
+
+// parseACL parses the entries in the short or long text form (e.g., "u::rw-"
+// or "group:wheel:r--"). Names are resolved to ids. The mask is computed the
+// way setfacl does it if there are named entries, but no mask.
+func parseACL(texts []string) ([]aclEntry, error) {
+	entries := make([]aclEntry, 0, len(texts)+1)
+	for _, text := range texts {
+		entry, err := parseACLEntry(text)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	has := func(tag uint16) bool {
+		return slices.ContainsFunc(entries, func(e aclEntry) bool { return e.tag == tag })
+	}
+
+	for _, tag := range []uint16{aclUserObj, aclGroupObj, aclOther} {
+		if !has(tag) {
+			return nil, fmt.Errorf("acl is missing required entry: %s", aclEntry{tag: tag, id: aclNoId})
+		}
+	}
+
+	if (has(aclUser) || has(aclGroup)) && !has(aclMask) {
+		mask := aclEntry{tag: aclMask, id: aclNoId}
+		for _, entry := range entries {
+			if entry.tag == aclUser || entry.tag == aclGroupObj || entry.tag == aclGroup {
+				mask.perm |= entry.perm
+			}
+		}
+		entries = append(entries, mask)
+	}
+
+	sortACL(entries)
+	return entries, nil
+}
+
+func parseACLEntry(text string) (aclEntry, error) {
+	fields := strings.Split(text, ":")
+	if len(fields) != 3 {
+		return aclEntry{}, fmt.Errorf("malformed acl entry: %q", text)
+	}
+
+	entry := aclEntry{id: aclNoId}
+
+	switch fields[0] {
+	case "u", "user":
+		entry.tag = aclUserObj
+		if fields[1] != "" {
+			entry.tag = aclUser
+		}
+	case "g", "group":
+		entry.tag = aclGroupObj
+		if fields[1] != "" {
+			entry.tag = aclGroup
+		}
+	case "m", "mask":
+		entry.tag = aclMask
+	case "o", "other":
+		entry.tag = aclOther
+	default:
+		return aclEntry{}, fmt.Errorf("unknown acl entry tag: %q", text)
+	}
+
+	if fields[1] != "" {
+		id, err := resolveACLQualifier(entry.tag, fields[1])
+		if err != nil {
+			return aclEntry{}, err
+		}
+
+		entry.id = id
+	}
+
+	for _, c := range fields[2] {
+		switch c {
+		case 'r':
+			entry.perm |= 4
+		case 'w':
+			entry.perm |= 2
+		case 'x':
+			entry.perm |= 1
+		case '-':
+		default:
+			return aclEntry{}, fmt.Errorf("unknown acl permission: %q", text)
+		}
+	}
+
+	return entry, nil
+}
+
+func resolveACLQualifier(tag uint16, qualifier string) (uint32, error) {
+	id, err := strconv.ParseUint(qualifier, 10, 32)
+	if err == nil {
+		return uint32(id), nil
+	}
+
+	var resolved int
+	switch tag {
+	case aclUser:
+		resolved, err = lookupUser(qualifier)
+	case aclGroup:
+		resolved, err = lookupGroup(qualifier)
+	default:
+		return 0, fmt.Errorf("acl entry can't have qualifier: %q", qualifier)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve acl qualifier: %w", err)
+	}
+
+	return uint32(resolved), nil
+}
+
+func sortACL(entries []aclEntry) {
+	slices.SortFunc(entries, func(a, b aclEntry) int {
+		return cmp.Or(cmp.Compare(a.tag, b.tag), cmp.Compare(a.id, b.id))
+	})
+}
+
+func formatACL(entries []aclEntry) string {
+	texts := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		texts = append(texts, entry.String())
+	}
+
+	return strings.Join(texts, ",")
+}
+
+// aclFromMode returns the minimal ACL, which is equivalent to the mode. It's
+// what a file without an extended ACL effectively has.
+func aclFromMode(mode os.FileMode) []aclEntry {
+	return []aclEntry{
+		{tag: aclUserObj, id: aclNoId, perm: uint16(mode>>6) & 7},
+		{tag: aclGroupObj, id: aclNoId, perm: uint16(mode>>3) & 7},
+		{tag: aclOther, id: aclNoId, perm: uint16(mode) & 7},
+	}
+}
+
+// encodeACL produces the value of the "system.posix_acl_access" xattr.
+func encodeACL(entries []aclEntry) []byte {
+	data := binary.LittleEndian.AppendUint32(nil, aclVersion)
+	for _, entry := range entries {
+		data = binary.LittleEndian.AppendUint16(data, entry.tag)
+		data = binary.LittleEndian.AppendUint16(data, entry.perm)
+		data = binary.LittleEndian.AppendUint32(data, entry.id)
+	}
+
+	return data
+}
+
+func decodeACL(data []byte) ([]aclEntry, error) {
+	if len(data) < 4 || (len(data)-4)%8 != 0 {
+		return nil, fmt.Errorf("malformed acl of size %d", len(data))
+	}
+
+	version := binary.LittleEndian.Uint32(data)
+	if version != aclVersion {
+		return nil, fmt.Errorf("unsupported acl version: %d", version)
+	}
+
+	entries := make([]aclEntry, 0, (len(data)-4)/8)
+	for offset := 4; offset < len(data); offset += 8 {
+		entries = append(entries, aclEntry{
+			tag:  binary.LittleEndian.Uint16(data[offset:]),
+			perm: binary.LittleEndian.Uint16(data[offset+2:]),
+			id:   binary.LittleEndian.Uint32(data[offset+4:]),
+		})
+	}
+
+	sortACL(entries)
+	return entries, nil
+}
+
+// Inode flags (see chattr(1)) keyed by their chattr letters.
+var inodeFlags = map[byte]uint32{
+	's': 0x00000001, // secure deletion
+	'u': 0x00000002, // undeletable
+	'c': 0x00000004, // compressed
+	'S': 0x00000008, // synchronous updates
+	'i': 0x00000010, // immutable
+	'a': 0x00000020, // append only
+	'd': 0x00000040, // no dump
+	'A': 0x00000080, // no atime updates
+}
+
+// protectiveFlags prevent any change to the file, including the corrections.
+const protectiveFlags = 0x00000010 | 0x00000020
+
+// parseFlags parses the flags in chattr form (e.g., "+i", "-a", "+dA") into
+// the masks of the flags to be set and cleared.
+func parseFlags(texts []string) (uint32, uint32, error) {
+	var set, clear uint32
+
+	for _, text := range texts {
+		if len(text) < 2 || (text[0] != '+' && text[0] != '-') {
+			return 0, 0, fmt.Errorf("malformed flags: %q", text)
+		}
+
+		for _, letter := range []byte(text[1:]) {
+			flag, ok := inodeFlags[letter]
+			if !ok {
+				return 0, 0, fmt.Errorf("unknown flag: %q", letter)
+			}
+
+			if text[0] == '+' {
+				set, clear = set|flag, clear&^flag
+			} else {
+				set, clear = set&^flag, clear|flag
+			}
+		}
+	}
+
+	return set, clear, nil
+}
+
+func formatFlags(flags uint32) string {
+	letters := make([]byte, 0)
+	for _, letter := range []byte("sucSiadA") {
+		if flags&inodeFlags[letter] != 0 {
+			letters = append(letters, letter)
+		}
+	}
+
+	return fmt.Sprintf("+%s", letters)
+}
@@ -0,0 +1,83 @@
+//go:build linux
+
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// getXattr returns the value of the extended attribute, and false if the file
+// does not have it.
+func getXattr(path, name string) ([]byte, bool, error) {
+	for {
+		size, err := unix.Getxattr(path, name, nil)
+		if errors.Is(err, unix.ENODATA) {
+			return nil, false, nil
+		}
+
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get xattr %s: %w", name, err)
+		}
+
+		value := make([]byte, size)
+		size, err = unix.Getxattr(path, name, value)
+
+		// NOTE: The value might have grown in between the calls.
+		if errors.Is(err, unix.ERANGE) {
+			continue
+		}
+
+		if errors.Is(err, unix.ENODATA) {
+			return nil, false, nil
+		}
+
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get xattr %s: %w", name, err)
+		}
+
+		return value[:size], true, nil
+	}
+}
+
+func setXattr(path, name string, value []byte) error {
+	err := unix.Setxattr(path, name, value, 0)
+	if err != nil {
+		return fmt.Errorf("failed to set xattr %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func getFlags(path string) (uint32, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	flags, err := unix.IoctlGetUint32(int(file.Fd()), unix.FS_IOC_GETFLAGS)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get flags: %w", err)
+	}
+
+	return flags, nil
+}
+
+func setFlags(path string, flags uint32) error {
+	file, err := os.OpenFile(path, os.O_RDONLY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	err = unix.IoctlSetPointerInt(int(file.Fd()), unix.FS_IOC_SETFLAGS, int(flags))
+	if err != nil {
+		return fmt.Errorf("failed to set flags: %w", err)
+	}
+
+	return nil
+}
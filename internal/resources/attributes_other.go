@@ -0,0 +1,21 @@
+//go:build !linux
+
+package resources
+
+import "errors"
+
+func getXattr(path, name string) ([]byte, bool, error) {
+	return nil, false, errors.ErrUnsupported
+}
+
+func setXattr(path, name string, value []byte) error {
+	return errors.ErrUnsupported
+}
+
+func getFlags(path string) (uint32, error) {
+	return 0, errors.ErrUnsupported
+}
+
+func setFlags(path string, flags uint32) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,147 @@
+package resources
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseACLUnit(t *testing.T) {
+	t.Run("minimal acl", func(t *testing.T) {
+		entries, err := parseACL([]string{"o::r--", "u::rw-", "g::r--"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, aclFromMode(0o644), entries)
+		assert.Equal(t, "user::rw-,group::r--,other::r--", formatACL(entries))
+	})
+
+	t.Run("extended acl gets mask", func(t *testing.T) {
+		entries, err := parseACL([]string{"user::rw-", "user:1001:r--", "group::r--", "group:20:-wx", "other::---"})
+
+		assert.NoError(t, err)
+		assert.Equal(
+			t,
+			"user::rw-,user:1001:r--,group::r--,group:20:-wx,mask::rwx,other::---",
+			formatACL(entries),
+		)
+	})
+
+	t.Run("acl is missing required entry", func(t *testing.T) {
+		_, err := parseACL([]string{"user::rw-", "group::r--"})
+
+		assert.ErrorContains(t, err, "acl is missing required entry")
+	})
+
+	t.Run("malformed acl entry", func(t *testing.T) {
+		_, err := parseACL([]string{"user:rw-"})
+
+		assert.ErrorContains(t, err, "malformed acl entry")
+	})
+
+	t.Run("acl round trip", func(t *testing.T) {
+		entries, err := parseACL([]string{"u::rwx", "u:1001:r-x", "g::r-x", "m::r-x", "o::---"})
+		assert.NoError(t, err)
+
+		decoded, err := decodeACL(encodeACL(entries))
+		assert.NoError(t, err)
+		assert.Equal(t, entries, decoded)
+	})
+}
+
+func TestParseFlagsUnit(t *testing.T) {
+	t.Run("flags to set and clear", func(t *testing.T) {
+		set, clear, err := parseFlags([]string{"+ia", "-a", "-d"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, inodeFlags['i'], set)
+		assert.Equal(t, inodeFlags['a']|inodeFlags['d'], clear)
+		assert.Equal(t, "+i", formatFlags(set))
+	})
+
+	t.Run("unknown flag", func(t *testing.T) {
+		_, _, err := parseFlags([]string{"+z"})
+
+		assert.ErrorContains(t, err, "unknown flag")
+	})
+}
+
+func TestFileAttributesIntegration(t *testing.T) {
+	t.Run("file has wrong xattr", func(t *testing.T) {
+		path := testFilePath()
+
+		err := os.WriteFile(path, nil, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(path) })
+
+		file := NewFile(path, WithXattr("user.align", "value"))
+		expected := []Correction{file.changeXattrs}
+
+		actual, err := file.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		err = file.changeXattrs()
+		assert.NoError(t, err)
+
+		corrections, err := file.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("file has wrong acl", func(t *testing.T) {
+		path := testFilePath()
+
+		err := os.WriteFile(path, nil, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(path) })
+
+		file := NewFile(path, WithACL("user::rw-", "user:0:r--", "group::r--", "other::---"))
+		expected := []Correction{file.changeACL}
+
+		actual, err := file.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		err = file.changeACL()
+		assert.NoError(t, err)
+
+		corrections, err := file.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("immutable file is released before correction", func(t *testing.T) {
+		path := testFilePath()
+
+		err := os.WriteFile(path, []byte("actual"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { setFlags(path, 0); os.Remove(path) })
+
+		err = setFlags(path, inodeFlags['i'])
+		if err != nil {
+			t.Skipf("inode flags are not supported: %s", err)
+		}
+
+		file := NewFile(path, WithContent("target"), WithFlags("+i"))
+		expected := []Correction{file.releaseFlags, file.changeContent, file.changeFlags}
+
+		actual, err := file.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		for _, correction := range actual {
+			assert.NoError(t, correction())
+		}
+
+		corrections, err := file.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+}
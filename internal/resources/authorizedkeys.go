@@ -0,0 +1,576 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/scherepiuk/align/internal/logger"
+	"github.com/scherepiuk/align/internal/types"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	sshDirMode         = os.FileMode(0o700)
+	authorizedKeysMode = os.FileMode(0o600)
+)
+
+// AuthorizedKeys manages ~user/.ssh/authorized_keys of a managed user. In the
+// additive mode (the default) only the listed keys are managed and the rest of
+// the file is left alone, in the exclusive mode the listed keys are the only
+// ones allowed to log in.
+type AuthorizedKeys struct {
+	BaseDependant
+	user      *User
+	keys      []authorizedKeySpec
+	exclusive bool
+	backup    types.Optional[*Backup]
+}
+
+type authorizedKeySpec struct {
+	key     string
+	options []string
+}
+
+// NewAuthorizedKeys makes the user a dependency, so the keys are only
+// installed once the user (and the home directory, see WithCreateHome)
+// exists. The home directory itself is never created, a missing one is an
+// error.
+func NewAuthorizedKeys(user *User, opts ...AuthorizedKeysOption) *AuthorizedKeys {
+	authorizedKeys := &AuthorizedKeys{user: user}
+	authorizedKeys.SetDependencies(user)
+
+	for _, opt := range opts {
+		opt(authorizedKeys)
+	}
+
+	return authorizedKeys
+}
+
+type AuthorizedKeysOption func(authorizedKeys *AuthorizedKeys)
+
+// WithAuthorizedKey adds the public key (e.g., "ssh-ed25519 AAAA... comment")
+// with the options restricting it (e.g., `from="10.0.0.0/8"`, "no-pty").
+func WithAuthorizedKey(key string, options ...string) AuthorizedKeysOption {
+	return func(authorizedKeys *AuthorizedKeys) {
+		logger.Global().Info(
+			"specifying authorized key", "user", authorizedKeys.user.name,
+			"key", key, "options", options,
+		)
+		authorizedKeys.keys = append(authorizedKeys.keys, authorizedKeySpec{key: key, options: options})
+	}
+}
+
+// WithExclusiveKeys removes all the keys that are not listed.
+func WithExclusiveKeys() AuthorizedKeysOption {
+	return func(authorizedKeys *AuthorizedKeys) {
+		logger.Global().Info("specifying exclusive authorized keys", "user", authorizedKeys.user.name)
+		authorizedKeys.exclusive = true
+	}
+}
+
+// WithAuthorizedKeysBackup saves the file before the keys are changed.
+func WithAuthorizedKeysBackup(backup *Backup) AuthorizedKeysOption {
+	return func(authorizedKeys *AuthorizedKeys) {
+		logger.Global().Info("specifying authorized keys backup", "user", authorizedKeys.user.name, "dir", backup.dir)
+		authorizedKeys.backup = types.NewOptional(backup)
+	}
+}
+
+func (a *AuthorizedKeys) Id() string {
+	return fmt.Sprintf("~%s/.ssh/authorized_keys", a.user.name)
+}
+
+func (a *AuthorizedKeys) Check() ([]Correction, error) {
+	passwd, err := a.user.backend.lookupPasswd(a.user.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup passwd entry: %w", err)
+	}
+
+	dir, path := a.paths(passwd)
+
+	sshDir, err := a.openSSHDir(passwd, false)
+
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Global().Warn("ssh directory does not exist", "user", a.user.name, "path", dir)
+		corrections := []Correction{
+			a.fixDirectory,
+			a.changeKeys,
+			a.fixFile,
+		}
+		return corrections, ErrUnalignedResource
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer sshDir.Close()
+
+	dirStat, err := sshDir.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat ssh directory: %w", err)
+	}
+
+	corrections := make([]Correction, 0)
+
+	if drift := ownershipDrift(dirStat, sshDirMode, passwd); drift != "" {
+		logger.Global().Warn("ssh directory "+drift, "user", a.user.name, "path", dir)
+		corrections = append(corrections, a.fixDirectory)
+	}
+
+	file, err := openKeysFile(sshDir, os.O_RDONLY)
+
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Global().Warn("authorized keys file does not exist", "user", a.user.name, "path", path)
+		corrections = append(corrections, a.changeKeys, a.fixFile)
+		return corrections, ErrUnalignedResource
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fileStat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat authorized keys file: %w", err)
+	}
+
+	lines, err := readKeysFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	_, drift, err := a.merge(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	if drift != "" {
+		logger.Global().Warn(drift, "user", a.user.name, "path", path)
+		corrections = append(corrections, a.changeKeys)
+	}
+
+	if drift := ownershipDrift(fileStat, authorizedKeysMode, passwd); drift != "" {
+		logger.Global().Warn("authorized keys file "+drift, "user", a.user.name, "path", path)
+		corrections = append(corrections, a.fixFile)
+	}
+
+	if len(corrections) > 0 {
+		return corrections, ErrUnalignedResource
+	}
+
+	return nil, nil
+}
+
+// Watch observes the authorized keys file only, changes of the ssh directory
+// are caught when the file is checked next.
+func (a *AuthorizedKeys) Watch(
+	ctx context.Context,
+	correctionsCh chan<- []Correction,
+	errCh chan<- error,
+) {
+	passwd, err := a.user.backend.lookupPasswd(a.user.name)
+	if err != nil {
+		errCh <- fmt.Errorf("failed to lookup passwd entry: %w", err)
+		return
+	}
+
+	_, path := a.paths(passwd)
+	watchFile(ctx, a, fileWatch{path: path}, correctionsCh, errCh)
+}
+
+// merge returns the lines of the file with the managed keys aligned, and the
+// description of the drift, which is empty if nothing had to be changed.
+func (a *AuthorizedKeys) merge(lines []string) ([]string, string, error) {
+	desired := make([]authorizedKey, 0, len(a.keys))
+	for _, spec := range a.keys {
+		key, err := parseAuthorizedKey(spec.key)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse authorized key: %w", err)
+		}
+
+		key.options = strings.Join(spec.options, ",")
+		desired = append(desired, key)
+	}
+
+	merged := make([]string, 0, len(lines)+len(desired))
+	placed := make(map[string]bool, len(desired))
+	drift := ""
+
+	for _, line := range lines {
+		actual, err := parseAuthorizedKey(line)
+		if err != nil {
+			// NOTE: Comments, blank lines and garbage are kept in the
+			// additive mode, they can't grant access anyway.
+			if !a.exclusive {
+				merged = append(merged, line)
+			} else if strings.TrimSpace(line) != "" {
+				drift = "authorized keys file has unexpected lines"
+			}
+			continue
+		}
+
+		i := indexAuthorizedKey(desired, actual)
+		if i == -1 {
+			if a.exclusive {
+				drift = "authorized keys file has unexpected keys"
+				continue
+			}
+
+			merged = append(merged, line)
+			continue
+		}
+
+		if placed[actual.id()] {
+			drift = "authorized keys file has duplicated keys"
+			continue
+		}
+
+		placed[actual.id()] = true
+		merged = append(merged, desired[i].String())
+
+		if line != desired[i].String() {
+			drift = "authorized key has wrong options or comment"
+		}
+	}
+
+	for _, key := range desired {
+		if !placed[key.id()] {
+			placed[key.id()] = true
+			merged = append(merged, key.String())
+			drift = "authorized key is missing"
+		}
+	}
+
+	return merged, drift, nil
+}
+
+func (a *AuthorizedKeys) fixDirectory() error {
+	passwd, err := a.user.backend.lookupPasswd(a.user.name)
+	if err != nil {
+		return fmt.Errorf("failed to lookup passwd entry: %w", err)
+	}
+
+	sshDir, err := a.openSSHDir(passwd, true)
+	if err != nil {
+		return err
+	}
+	defer sshDir.Close()
+
+	return fixOwnership(sshDir, sshDirMode, passwd)
+}
+
+// changeKeys rewrites the file in place, through the descriptor it was read
+// from, so it can't be swapped in between.
+func (a *AuthorizedKeys) changeKeys() error {
+	passwd, err := a.user.backend.lookupPasswd(a.user.name)
+	if err != nil {
+		return fmt.Errorf("failed to lookup passwd entry: %w", err)
+	}
+
+	sshDir, err := a.openSSHDir(passwd, false)
+	if err != nil {
+		return err
+	}
+	defer sshDir.Close()
+
+	file, err := openKeysFile(sshDir, os.O_RDWR)
+	missing := errors.Is(err, os.ErrNotExist)
+
+	if missing {
+		file, err = openKeysFile(sshDir, os.O_RDWR|os.O_CREATE|os.O_EXCL)
+	}
+
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	original, err := readKeysFile(file)
+	if err != nil {
+		return err
+	}
+
+	lines, drift, err := a.merge(original)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: A missing file is created even without drift, e.g., in the
+	// exclusive mode without keys.
+	if drift == "" && !missing {
+		return nil
+	}
+
+	// NOTE: The content read through the descriptor is saved, rather than
+	// the path, which is not safe to follow.
+	if a.backup.Ok() && len(original) > 0 {
+		err = a.backup.Value().SaveContent(a.Id(), []byte(strings.Join(original, "\n")+"\n"))
+		if err != nil {
+			return fmt.Errorf("failed to back up authorized keys file: %w", err)
+		}
+	}
+
+	content := ""
+	if len(lines) > 0 {
+		content = strings.Join(lines, "\n") + "\n"
+	}
+
+	err = file.Truncate(0)
+	if err != nil {
+		return fmt.Errorf("failed to truncate authorized keys file: %w", err)
+	}
+
+	_, err = file.WriteAt([]byte(content), 0)
+	if err != nil {
+		return fmt.Errorf("failed to write authorized keys file: %w", err)
+	}
+
+	return nil
+}
+
+func (a *AuthorizedKeys) fixFile() error {
+	passwd, err := a.user.backend.lookupPasswd(a.user.name)
+	if err != nil {
+		return fmt.Errorf("failed to lookup passwd entry: %w", err)
+	}
+
+	sshDir, err := a.openSSHDir(passwd, false)
+	if err != nil {
+		return err
+	}
+	defer sshDir.Close()
+
+	file, err := openKeysFile(sshDir, os.O_RDONLY)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return fixOwnership(file, authorizedKeysMode, passwd)
+}
+
+func (a *AuthorizedKeys) paths(passwd passwdEntry) (string, string) {
+	dir := a.user.backend.path(filepath.Join(passwd.home, ".ssh"))
+	return dir, filepath.Join(dir, "authorized_keys")
+}
+
+// openSSHDir opens the ssh directory relative to the home directory, without
+// following a symlink, since the user could point it anywhere (e.g., to /etc)
+// and have align change that instead. The directory is created if asked to.
+func (a *AuthorizedKeys) openSSHDir(passwd passwdEntry, create bool) (*os.File, error) {
+	homePath := a.user.backend.path(passwd.home)
+
+	// NOTE: The error does not wrap os.ErrNotExist, so it's not mistaken for
+	// a missing ssh directory, which would be created.
+	home, err := os.Open(homePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("home directory of %s does not exist: %s", a.user.name, homePath)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open home: %w", err)
+	}
+	defer home.Close()
+
+	if create {
+		err := unix.Mkdirat(int(home.Fd()), ".ssh", uint32(sshDirMode))
+		if err != nil && !errors.Is(err, unix.EEXIST) {
+			return nil, fmt.Errorf("failed to create ssh directory: %w", err)
+		}
+	}
+
+	return openNoFollow(home, ".ssh", unix.O_RDONLY|unix.O_DIRECTORY, 0)
+}
+
+// openKeysFile opens the authorized keys file without following a symlink,
+// and refuses anything but a regular file with a single link, as a hard link
+// would make align change the file it's linked to (e.g., /etc/shadow).
+func openKeysFile(sshDir *os.File, flags int) (*os.File, error) {
+	// NOTE: O_NONBLOCK keeps a FIFO from blocking the open.
+	file, err := openNoFollow(sshDir, "authorized_keys", flags|unix.O_NONBLOCK, uint32(authorizedKeysMode))
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat authorized keys file: %w", err)
+	}
+
+	if !stat.Mode().IsRegular() {
+		file.Close()
+		return nil, fmt.Errorf("authorized keys file is not a regular file: %s", file.Name())
+	}
+
+	if linux, ok := stat.Sys().(*syscall.Stat_t); ok && linux.Nlink > 1 {
+		file.Close()
+		return nil, fmt.Errorf("refusing hard-linked authorized keys file: %s", file.Name())
+	}
+
+	return file, nil
+}
+
+func readKeysFile(file *os.File) ([]string, error) {
+	content, err := io.ReadAll(io.NewSectionReader(file, 0, 1<<62))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authorized keys file: %w", err)
+	}
+
+	if len(content) == 0 {
+		return []string{}, nil
+	}
+
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n"), nil
+}
+
+// openNoFollow opens the name relative to the directory, failing if it's a
+// symlink.
+func openNoFollow(dir *os.File, name string, flags int, mode uint32) (*os.File, error) {
+	path := filepath.Join(dir.Name(), name)
+
+	fd, err := unix.Openat(int(dir.Fd()), name, flags|unix.O_NOFOLLOW|unix.O_CLOEXEC, mode)
+
+	// NOTE: With O_DIRECTORY, a symlink fails with ENOTDIR instead of ELOOP.
+	if errors.Is(err, unix.ELOOP) || errors.Is(err, unix.ENOTDIR) {
+		var stat unix.Stat_t
+		if unix.Fstatat(int(dir.Fd()), name, &stat, unix.AT_SYMLINK_NOFOLLOW) == nil && stat.Mode&unix.S_IFMT == unix.S_IFLNK {
+			return nil, fmt.Errorf("refusing to follow symlink: %s", path)
+		}
+	}
+
+	if err != nil {
+		return nil, &os.PathError{Op: "openat", Path: path, Err: err}
+	}
+
+	return os.NewFile(uintptr(fd), path), nil
+}
+
+// ownershipDrift describes how the mode or the ownership of the path differ
+// from what sshd expects, it's empty if they don't.
+func ownershipDrift(stat os.FileInfo, mode os.FileMode, passwd passwdEntry) string {
+	if permissionBits(stat.Mode()) != mode {
+		return "has wrong mode"
+	}
+
+	uid, gid := fileOwnership(stat)
+	if uid != passwd.uid || gid != passwd.gid {
+		return "has wrong ownership"
+	}
+
+	return ""
+}
+
+// fixOwnership changes the opened file, so a symlink can't redirect it.
+func fixOwnership(file *os.File, mode os.FileMode, passwd passwdEntry) error {
+	err := file.Chmod(mode)
+	if err != nil {
+		return fmt.Errorf("failed to change mode: %w", err)
+	}
+
+	err = file.Chown(passwd.uid, passwd.gid)
+	if err != nil {
+		return fmt.Errorf("failed to change ownership: %w", err)
+	}
+
+	return nil
+}
+
+// authorizedKey is a line of the authorized_keys file (see sshd(8)).
+type authorizedKey struct {
+	options string
+	keyType string
+	blob    string
+	comment string
+}
+
+// id identifies the key regardless of its options and comment.
+func (k authorizedKey) id() string {
+	return k.keyType + " " + k.blob
+}
+
+func (k authorizedKey) String() string {
+	fields := make([]string, 0, 4)
+	if k.options != "" {
+		fields = append(fields, k.options)
+	}
+
+	fields = append(fields, k.keyType, k.blob)
+	if k.comment != "" {
+		fields = append(fields, k.comment)
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func parseAuthorizedKey(line string) (authorizedKey, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return authorizedKey{}, fmt.Errorf("not an authorized key: %q", line)
+	}
+
+	key := authorizedKey{}
+
+	// NOTE: Options come first and may contain quoted spaces, they are told
+	// apart from the key type by the type being a known one.
+	if !isAuthorizedKeyType(strings.Fields(line)[0]) {
+		end := optionsEnd(line)
+		key.options, line = line[:end], strings.TrimSpace(line[end:])
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 || !isAuthorizedKeyType(fields[0]) {
+		return authorizedKey{}, fmt.Errorf("malformed authorized key: %q", line)
+	}
+
+	key.keyType, key.blob = fields[0], fields[1]
+	key.comment = strings.Join(fields[2:], " ")
+
+	return key, nil
+}
+
+func isAuthorizedKeyType(field string) bool {
+	for _, prefix := range []string{"ssh-", "ecdsa-", "sk-"} {
+		if strings.HasPrefix(field, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// optionsEnd returns the index of the first whitespace outside of quotes.
+func optionsEnd(line string) int {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ' ', '\t':
+			if !quoted {
+				return i
+			}
+		}
+	}
+
+	return len(line)
+}
+
+func indexAuthorizedKey(keys []authorizedKey, key authorizedKey) int {
+	for i := range keys {
+		if keys[i].id() == key.id() {
+			return i
+		}
+	}
+
+	return -1
+}
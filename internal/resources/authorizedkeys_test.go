@@ -0,0 +1,251 @@
+package resources
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAuthorizedKeyUnit(t *testing.T) {
+	type testCase struct {
+		name     string
+		line     string
+		expected authorizedKey
+	}
+
+	testCases := []testCase{
+		{
+			name:     "key without comment",
+			line:     "ssh-ed25519 AAAAC3Nza",
+			expected: authorizedKey{keyType: "ssh-ed25519", blob: "AAAAC3Nza"},
+		},
+		{
+			name:     "key with comment",
+			line:     "ssh-rsa AAAAB3Nza first@host  and more",
+			expected: authorizedKey{keyType: "ssh-rsa", blob: "AAAAB3Nza", comment: "first@host and more"},
+		},
+		{
+			name: "key with quoted options",
+			line: `from="10.0.0.1, 10.0.0.2",command="echo \"hi there\"",no-pty ecdsa-sha2-nistp256 AAAAE2Vj deploy`,
+			expected: authorizedKey{
+				options: `from="10.0.0.1, 10.0.0.2",command="echo \"hi there\"",no-pty`,
+				keyType: "ecdsa-sha2-nistp256",
+				blob:    "AAAAE2Vj",
+				comment: "deploy",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := parseAuthorizedKey(tc.line)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	t.Run("comment is not a key", func(t *testing.T) {
+		_, err := parseAuthorizedKey("# ssh-ed25519 AAAAC3Nza")
+
+		assert.ErrorContains(t, err, "not an authorized key")
+	})
+
+	t.Run("malformed key", func(t *testing.T) {
+		_, err := parseAuthorizedKey("no-pty AAAAC3Nza")
+
+		assert.ErrorContains(t, err, "malformed authorized key")
+	})
+}
+
+func TestAuthorizedKeysMergeUnit(t *testing.T) {
+	user := NewUser("testing", WithUid(42069), WithGid(1000))
+
+	type testCase struct {
+		name          string
+		opts          []AuthorizedKeysOption
+		lines         []string
+		expected      []string
+		expectedDrift bool
+	}
+
+	testCases := []testCase{
+		{
+			name: "additive mode keeps foreign keys",
+			opts: []AuthorizedKeysOption{WithAuthorizedKey("ssh-ed25519 AAAA first")},
+			lines: []string{
+				"# managed elsewhere",
+				"ssh-rsa BBBB second",
+			},
+			expected: []string{
+				"# managed elsewhere",
+				"ssh-rsa BBBB second",
+				"ssh-ed25519 AAAA first",
+			},
+			expectedDrift: true,
+		},
+		{
+			name:  "additive mode replaces options in place",
+			opts:  []AuthorizedKeysOption{WithAuthorizedKey("ssh-ed25519 AAAA first", "no-pty")},
+			lines: []string{"ssh-ed25519 AAAA first", "ssh-rsa BBBB second"},
+			expected: []string{
+				"no-pty ssh-ed25519 AAAA first",
+				"ssh-rsa BBBB second",
+			},
+			expectedDrift: true,
+		},
+		{
+			name:     "aligned keys",
+			opts:     []AuthorizedKeysOption{WithAuthorizedKey("ssh-ed25519 AAAA first", "no-pty")},
+			lines:    []string{"ssh-rsa BBBB second", "no-pty ssh-ed25519 AAAA first"},
+			expected: []string{"ssh-rsa BBBB second", "no-pty ssh-ed25519 AAAA first"},
+		},
+		{
+			name: "exclusive mode removes foreign keys",
+			opts: []AuthorizedKeysOption{
+				WithAuthorizedKey("ssh-ed25519 AAAA first"),
+				WithExclusiveKeys(),
+			},
+			lines: []string{
+				"# managed elsewhere",
+				"ssh-rsa BBBB second",
+				"ssh-ed25519 AAAA first",
+				"ssh-ed25519 AAAA duplicate",
+			},
+			expected:      []string{"ssh-ed25519 AAAA first"},
+			expectedDrift: true,
+		},
+		{
+			name:          "exclusive mode without keys",
+			opts:          []AuthorizedKeysOption{WithExclusiveKeys()},
+			lines:         []string{"ssh-rsa BBBB second"},
+			expected:      []string{},
+			expectedDrift: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			authorizedKeys := NewAuthorizedKeys(user, tc.opts...)
+
+			actual, drift, err := authorizedKeys.merge(tc.lines)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.expectedDrift, drift != "")
+		})
+	}
+}
+
+func TestNewAuthorizedKeysUnit(t *testing.T) {
+	user := NewUser("testing", WithUid(42069), WithGid(1000))
+	authorizedKeys := NewAuthorizedKeys(user)
+
+	assert.Equal(t, "~testing/.ssh/authorized_keys", authorizedKeys.Id())
+	assert.Equal(t, []Resource{user}, authorizedKeys.Dependencies())
+}
+
+func TestAuthorizedKeysLinksUnit(t *testing.T) {
+	setup := func(t *testing.T) (*AuthorizedKeys, string) {
+		root := testAccountsRoot(t)
+		backend := NewNativeBackend(root)
+
+		user := NewUser("testing", WithUid(42069), WithGid(1000), WithUserBackend(backend))
+		assert.NoError(t, user.create())
+
+		passwd, err := backend.lookupPasswd("testing")
+		assert.NoError(t, err)
+
+		home := backend.path(passwd.home)
+		assert.NoError(t, os.MkdirAll(home, 0o700))
+
+		return NewAuthorizedKeys(user, WithAuthorizedKey("ssh-ed25519 AAAA testing")), home
+	}
+
+	t.Run("symlinked ssh directory is refused", func(t *testing.T) {
+		authorizedKeys, home := setup(t)
+
+		target := t.TempDir()
+		assert.NoError(t, os.Symlink(target, filepath.Join(home, ".ssh")))
+
+		_, err := authorizedKeys.Check()
+		assert.ErrorContains(t, err, "refusing to follow symlink")
+
+		assert.Error(t, authorizedKeys.fixDirectory())
+		assert.Error(t, authorizedKeys.changeKeys())
+		assert.NoFileExists(t, filepath.Join(target, "authorized_keys"))
+	})
+
+	t.Run("symlinked authorized keys file is refused", func(t *testing.T) {
+		authorizedKeys, home := setup(t)
+
+		target := filepath.Join(t.TempDir(), "target")
+		assert.NoError(t, os.WriteFile(target, []byte("untouched\n"), 0o644))
+
+		assert.NoError(t, os.Mkdir(filepath.Join(home, ".ssh"), 0o700))
+		assert.NoError(t, os.Symlink(target, filepath.Join(home, ".ssh", "authorized_keys")))
+
+		_, err := authorizedKeys.Check()
+		assert.ErrorContains(t, err, "refusing to follow symlink")
+
+		assert.Error(t, authorizedKeys.changeKeys())
+		assert.Error(t, authorizedKeys.fixFile())
+
+		content, err := os.ReadFile(target)
+		assert.NoError(t, err)
+		assert.Equal(t, "untouched\n", string(content))
+	})
+
+	t.Run("hard-linked authorized keys file is refused", func(t *testing.T) {
+		authorizedKeys, home := setup(t)
+
+		target := filepath.Join(home, "target")
+		assert.NoError(t, os.WriteFile(target, []byte("untouched\n"), 0o644))
+
+		assert.NoError(t, os.Mkdir(filepath.Join(home, ".ssh"), 0o700))
+		assert.NoError(t, os.Link(target, filepath.Join(home, ".ssh", "authorized_keys")))
+
+		_, err := authorizedKeys.Check()
+		assert.ErrorContains(t, err, "refusing hard-linked")
+
+		assert.Error(t, authorizedKeys.changeKeys())
+
+		content, err := os.ReadFile(target)
+		assert.NoError(t, err)
+		assert.Equal(t, "untouched\n", string(content))
+	})
+
+	t.Run("missing ssh directory and file are created", func(t *testing.T) {
+		authorizedKeys, home := setup(t)
+
+		corrections, err := authorizedKeys.Check()
+		assertCorrections(t, []Correction{authorizedKeys.fixDirectory, authorizedKeys.changeKeys, authorizedKeys.fixFile}, corrections)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, authorizedKeys.fixDirectory())
+		assert.NoError(t, authorizedKeys.changeKeys())
+		assert.NoError(t, authorizedKeys.fixFile())
+
+		content, err := os.ReadFile(filepath.Join(home, ".ssh", "authorized_keys"))
+		assert.NoError(t, err)
+		assert.Equal(t, "ssh-ed25519 AAAA testing\n", string(content))
+
+		corrections, err = authorizedKeys.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("missing home is not created", func(t *testing.T) {
+		authorizedKeys, home := setup(t)
+		assert.NoError(t, os.Remove(home))
+
+		corrections, err := authorizedKeys.Check()
+		assert.Nil(t, corrections)
+		assert.ErrorContains(t, err, "home directory of testing does not exist")
+
+		assert.Error(t, authorizedKeys.fixDirectory())
+		assert.NoDirExists(t, home)
+	})
+}
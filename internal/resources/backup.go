@@ -0,0 +1,235 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/scherepiuk/align/internal/logger"
+	"github.com/scherepiuk/align/internal/types"
+)
+
+const backupTimeLayout = "20060102T150405.000000000Z"
+
+// Backup keeps copies of files that are about to be overwritten or removed
+// by corrections. Copies are named after the resource and the time they were
+// taken at, only the most recent ones (up to retention) are kept.
+type Backup struct {
+	dir       string
+	retention int // zero (or less) keeps all the backups
+	now       func() time.Time
+}
+
+func NewBackup(dir string, retention int) *Backup {
+	return &Backup{dir: dir, retention: retention, now: time.Now}
+}
+
+// Save copies the file, the symlink or the whole directory at the path. It
+// does nothing if there is nothing at the path.
+func (b *Backup) Save(id, path string) error {
+	_, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to stat backed up path: %w", err)
+	}
+
+	err = os.MkdirAll(b.dir, 0o700)
+	if err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	prefix := b.prefix(id)
+	backupPath := filepath.Join(b.dir, prefix+b.now().UTC().Format(backupTimeLayout))
+
+	logger.Global().Info("backing up", "id", id, "path", path, "backup", backupPath)
+
+	err = copyTree(path, backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to copy to backup: %w", err)
+	}
+
+	err = b.prune(prefix)
+	if err != nil {
+		return fmt.Errorf("failed to prune backups: %w", err)
+	}
+
+	return nil
+}
+
+// SaveContent saves the content that is not a file of its own (e.g., a user
+// crontab) the same way Save saves a file.
+func (b *Backup) SaveContent(id string, content []byte) error {
+	err := os.MkdirAll(b.dir, 0o700)
+	if err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	prefix := b.prefix(id)
+	backupPath := filepath.Join(b.dir, prefix+b.now().UTC().Format(backupTimeLayout))
+
+	logger.Global().Info("backing up", "id", id, "backup", backupPath)
+
+	err = os.WriteFile(backupPath, content, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	err = b.prune(prefix)
+	if err != nil {
+		return fmt.Errorf("failed to prune backups: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the paths of the backups of the resource, oldest first.
+func (b *Backup) List(id string) ([]string, error) {
+	return b.list(b.prefix(id))
+}
+
+func (b *Backup) list(prefix string) ([]string, error) {
+	entries, err := os.ReadDir(b.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	backups := make([]string, 0)
+	for _, entry := range entries {
+		timestamp, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok {
+			continue
+		}
+
+		// NOTE: Prefix of one resource may be a prefix of another one too
+		// (e.g., "/etc/app" and "/etc/app.conf"), the rest has to be a time.
+		_, err := time.Parse(backupTimeLayout, timestamp)
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, filepath.Join(b.dir, entry.Name()))
+	}
+
+	slices.Sort(backups)
+	return backups, nil
+}
+
+func (b *Backup) prune(prefix string) error {
+	if b.retention <= 0 {
+		return nil
+	}
+
+	backups, err := b.list(prefix)
+	if err != nil {
+		return err
+	}
+
+	for len(backups) > b.retention {
+		logger.Global().Info("removing old backup", "backup", backups[0])
+
+		err = os.RemoveAll(backups[0])
+		if err != nil {
+			return fmt.Errorf("failed to remove old backup: %w", err)
+		}
+
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+// saveBackup saves the path before a correction overwrites it, if the
+// resource has a backup.
+func saveBackup(backup types.Optional[*Backup], id, path string) error {
+	if !backup.Ok() {
+		return nil
+	}
+
+	err := backup.Value().Save(id, path)
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// prefix escapes the id (e.g., "/etc/a/b" as "%2Fetc%2Fa%2Fb"), so it can't
+// collide with the one of another id.
+func (b *Backup) prefix(id string) string {
+	return url.PathEscape(id) + "."
+}
+
+// copyTree copies regular files, symlinks and directories, preserving modes.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		stat, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case stat.IsDir():
+			return os.Mkdir(target, permissionBits(stat.Mode()))
+
+		case stat.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			return os.Symlink(link, target)
+
+		case stat.Mode().IsRegular():
+			return copyFile(path, target, permissionBits(stat.Mode()))
+
+		default:
+			logger.Global().Warn("skipping special file", "path", path)
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,179 @@
+package resources
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupIntegration(t *testing.T) {
+	t.Run("backups beyond retention are removed", func(t *testing.T) {
+		dir, path := t.TempDir(), filepath.Join(t.TempDir(), "file")
+
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		backup := NewBackup(dir, 2)
+		backup.now = func() time.Time { now = now.Add(time.Second); return now }
+
+		for _, content := range []string{"first", "second", "third"} {
+			err := os.WriteFile(path, []byte(content), 0o640)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = backup.Save(path, path)
+			assert.NoError(t, err)
+		}
+
+		backups, err := backup.List(path)
+		assert.NoError(t, err)
+
+		if assert.Len(t, backups, 2) {
+			content, err := os.ReadFile(backups[0])
+			assert.NoError(t, err)
+			assert.Equal(t, "second", string(content))
+
+			content, err = os.ReadFile(backups[1])
+			assert.NoError(t, err)
+			assert.Equal(t, "third", string(content))
+
+			stat, err := os.Stat(backups[1])
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o640), stat.Mode())
+		}
+	})
+
+	t.Run("backups of similar ids are kept apart", func(t *testing.T) {
+		dir := t.TempDir()
+		backup := NewBackup(dir, 1)
+
+		assert.NoError(t, backup.SaveContent("/etc/a_b", []byte("underscore")))
+		assert.NoError(t, backup.SaveContent("/etc/a/b", []byte("slash")))
+
+		for id, expected := range map[string]string{"/etc/a_b": "underscore", "/etc/a/b": "slash"} {
+			backups, err := backup.List(id)
+			assert.NoError(t, err)
+
+			if assert.Len(t, backups, 1) {
+				content, err := os.ReadFile(backups[0])
+				assert.NoError(t, err)
+				assert.Equal(t, expected, string(content))
+			}
+		}
+	})
+
+	t.Run("directory is backed up", func(t *testing.T) {
+		dir, path := t.TempDir(), t.TempDir()
+
+		err := os.WriteFile(filepath.Join(path, "file"), []byte("content"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		backup := NewBackup(dir, 0)
+
+		err = backup.Save("directory", path)
+		assert.NoError(t, err)
+
+		backups, err := backup.List("directory")
+		assert.NoError(t, err)
+
+		if assert.Len(t, backups, 1) {
+			content, err := os.ReadFile(filepath.Join(backups[0], "file"))
+			assert.NoError(t, err)
+			assert.Equal(t, "content", string(content))
+		}
+	})
+
+	t.Run("nothing to back up", func(t *testing.T) {
+		dir := t.TempDir()
+
+		backup := NewBackup(dir, 0)
+
+		err := backup.Save("missing", testFilePath())
+		assert.NoError(t, err)
+
+		backups, err := backup.List("missing")
+		assert.NoError(t, err)
+		assert.Empty(t, backups)
+	})
+
+	t.Run("file is backed up before its content is restored", func(t *testing.T) {
+		dir, path := t.TempDir(), filepath.Join(t.TempDir(), "file")
+
+		err := os.WriteFile(path, []byte("actual"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		file := NewFile(path, WithContent("target"), WithBackup(NewBackup(dir, 1)))
+
+		err = file.changeContent()
+		assert.NoError(t, err)
+
+		backups, err := file.backup.Value().List(file.Id())
+		assert.NoError(t, err)
+
+		if assert.Len(t, backups, 1) {
+			content, err := os.ReadFile(backups[0])
+			assert.NoError(t, err)
+			assert.Equal(t, "actual", string(content))
+		}
+	})
+
+	t.Run("retargeted symlink is backed up", func(t *testing.T) {
+		dir, path := t.TempDir(), filepath.Join(t.TempDir(), "link")
+
+		err := os.Symlink("/actual", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		symlink := NewSymlink(path, "/tmp", WithSymlinkBackup(NewBackup(dir, 1)))
+
+		err = symlink.retarget()
+		assert.NoError(t, err)
+
+		backups, err := symlink.backup.Value().List(symlink.Id())
+		assert.NoError(t, err)
+
+		if assert.Len(t, backups, 1) {
+			target, err := os.Readlink(backups[0])
+			assert.NoError(t, err)
+			assert.Equal(t, "/actual", target)
+		}
+	})
+
+	t.Run("changed line and config key are backed up", func(t *testing.T) {
+		dir, path := t.TempDir(), filepath.Join(t.TempDir(), "config.ini")
+
+		err := os.WriteFile(path, []byte("[server]\nport = 80\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		backup := NewBackup(dir, 0)
+		lineInFile, err := NewLineInFile(path, "# managed", WithLineBackup(backup))
+		assert.NoError(t, err)
+		configKey := NewConfigKey(path, "server.port", 8080, WithConfigKeyBackup(backup))
+
+		assert.NoError(t, lineInFile.changeLine())
+		assert.NoError(t, configKey.changeValue())
+
+		for id, expected := range map[string]string{
+			lineInFile.Id(): "[server]\nport = 80\n",
+			configKey.Id():  "[server]\nport = 80\n# managed\n",
+		} {
+			backups, err := backup.List(id)
+			assert.NoError(t, err)
+
+			if assert.Len(t, backups, 1) {
+				content, err := os.ReadFile(backups[0])
+				assert.NoError(t, err)
+				assert.Equal(t, expected, string(content))
+			}
+		}
+	})
+}
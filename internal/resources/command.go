@@ -0,0 +1,171 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// commandWaitDelay is how long the output is waited for once the command is
+// killed.
+const commandWaitDelay = time.Second
+
+// Command is a command to run, without a shell.
+type Command struct {
+	Name  string
+	Args  []string
+	Dir   string   // the working directory, the current one if empty
+	Env   []string // added to the environment of align, as "KEY=value"
+	User  string   // the user to run as, the one running align if empty
+	Stdin []byte
+}
+
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
+
+// CommandResult is what the command printed and how it exited.
+type CommandResult struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+// output returns what the command printed (stderr first), for errors and
+// logs.
+func (r CommandResult) output() string {
+	return strings.TrimSpace(string(r.Stderr) + string(r.Stdout))
+}
+
+// CommandRunner runs commands for the resources that drive external tools
+// (e.g., systemctl), so they can be tested with a fake one. The error is only
+// returned when the command could not be run at all, a non-zero exit code is
+// a part of the result.
+type CommandRunner interface {
+	Run(ctx context.Context, command Command) (CommandResult, error)
+}
+
+// NewExecRunner returns the runner executing the commands on the host.
+func NewExecRunner() CommandRunner {
+	return execRunner{}
+}
+
+type execRunner struct{}
+
+func (execRunner) Run(ctx context.Context, command Command) (CommandResult, error) {
+	cmd := exec.CommandContext(ctx, command.Name, command.Args...)
+	cmd.Dir = command.Dir
+	// NOTE: The command runs in its own process group, which is killed as a
+	// whole, so the children (e.g., of a shell) don't outlive it.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	// NOTE: Children escaping the process group may still keep the output
+	// open, which would block the run until they exit.
+	cmd.WaitDelay = commandWaitDelay
+
+	env := command.Env
+
+	if command.User != "" {
+		account, credential, err := userCredential(command.User)
+		if err != nil {
+			return CommandResult{}, err
+		}
+
+		cmd.SysProcAttr.Credential = credential
+		// NOTE: The environment of the user is set before the command's own,
+		// so the command can still override it.
+		env = append([]string{"HOME=" + account.HomeDir, "USER=" + account.Username, "LOGNAME=" + account.Username}, env...)
+	}
+
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	if command.Stdin != nil {
+		cmd.Stdin = bytes.NewReader(command.Stdin)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	result := CommandResult{Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}
+
+	if ctx.Err() != nil {
+		return result, fmt.Errorf("failed to run %s: %w", command.Name, ctx.Err())
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+		return result, nil
+	}
+
+	if err != nil {
+		return result, fmt.Errorf("failed to run %s: %w", command.Name, err)
+	}
+
+	return result, nil
+}
+
+// userCredential returns the account of the user, and its uid, gid and
+// supplementary groups.
+func userCredential(name string) (*user.User, *syscall.Credential, error) {
+	account, err := user.Lookup(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lookup user by name: %w", err)
+	}
+
+	uid, err := strconv.ParseUint(account.Uid, 10, 32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse uid: %w", err)
+	}
+
+	gid, err := strconv.ParseUint(account.Gid, 10, 32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse gid: %w", err)
+	}
+
+	groupIds, err := account.GroupIds()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lookup user's groups: %w", err)
+	}
+
+	groups := make([]uint32, 0, len(groupIds))
+	for _, groupId := range groupIds {
+		group, err := strconv.ParseUint(groupId, 10, 32)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse gid: %w", err)
+		}
+		groups = append(groups, uint32(group))
+	}
+
+	return account, &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: groups}, nil
+}
+
+// runChecked runs the command and treats a non-zero exit code as an error,
+// which includes what the command printed.
+func runChecked(ctx context.Context, runner CommandRunner, command Command) (CommandResult, error) {
+	result, err := runner.Run(ctx, command)
+	if err != nil {
+		return result, err
+	}
+
+	if result.ExitCode != 0 {
+		return result, fmt.Errorf("%s: exit status %d: %s", command, result.ExitCode, result.output())
+	}
+
+	return result, nil
+}
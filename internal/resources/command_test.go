@@ -0,0 +1,90 @@
+package resources
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRunner records the commands and answers them with the callback, or with
+// an empty successful result if there is none.
+type fakeRunner struct {
+	commands []string
+	answer   func(command Command) CommandResult
+}
+
+func (r *fakeRunner) Run(ctx context.Context, command Command) (CommandResult, error) {
+	r.commands = append(r.commands, command.String())
+
+	if r.answer == nil {
+		return CommandResult{}, nil
+	}
+
+	return r.answer(command), nil
+}
+
+func TestExecRunnerIntegration(t *testing.T) {
+	runner := NewExecRunner()
+
+	t.Run("output is captured", func(t *testing.T) {
+		result, err := runner.Run(context.Background(), Command{
+			Name:  "sh",
+			Args:  []string{"-c", `cat; echo "$GREETING" >&2; pwd; exit 3`},
+			Dir:   "/",
+			Env:   []string{"GREETING=hello"},
+			Stdin: []byte("input\n"),
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, CommandResult{Stdout: []byte("input\n/\n"), Stderr: []byte("hello\n"), ExitCode: 3}, result)
+	})
+
+	t.Run("non-zero exit code is an error when checked", func(t *testing.T) {
+		_, err := runChecked(context.Background(), runner, Command{Name: "sh", Args: []string{"-c", "echo oops >&2; false"}})
+
+		assert.ErrorContains(t, err, "sh -c echo oops >&2; false: exit status 1: oops")
+	})
+
+	t.Run("user's environment is set", func(t *testing.T) {
+		if os.Geteuid() != 0 {
+			t.Skip("running as another user requires root")
+		}
+
+		result, err := runner.Run(context.Background(), Command{
+			Name: "sh",
+			Args: []string{"-c", `echo "$HOME $USER $LOGNAME"; id -un`},
+			Dir:  "/",
+			User: "nobody",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "/nonexistent nobody nobody\nnobody\n", string(result.Stdout))
+	})
+
+	t.Run("children are killed on timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		result, err := runner.Run(ctx, Command{Name: "sh", Args: []string{"-c", "sleep 30 & echo $!; wait"}})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		pid := strings.TrimSpace(string(result.Stdout))
+		assert.NotEmpty(t, pid)
+
+		// NOTE: The killed child may linger as a zombie until it's reaped.
+		assert.Eventually(t, func() bool {
+			stat, err := os.ReadFile("/proc/" + pid + "/stat")
+			return err != nil || strings.Contains(string(stat), ") Z ")
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("missing command", func(t *testing.T) {
+		_, err := runner.Run(context.Background(), Command{Name: "align-missing-command"})
+
+		assert.ErrorContains(t, err, "failed to run align-missing-command")
+	})
+}
@@ -1,11 +1,60 @@
 package resources
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"os/user"
 	"strconv"
+	"syscall"
 )
 
+// check runs the checker and forwards the corrections to the channel if the
+// resource turns out to be unaligned.
+func check(checker Checker, correctionsCh chan<- []Correction) error {
+	corrections, err := checker.Check()
+	if errors.Is(err, ErrUnalignedResource) {
+		correctionsCh <- corrections
+		return nil
+	}
+
+	return err
+}
+
+// TODO: sc: Getting linux-specific file info. All resources should be cross-platform.
+func fileOwnership(stat os.FileInfo) (int, int) {
+	linuxFileInfo, ok := stat.Sys().(*syscall.Stat_t)
+	if !ok {
+		panic("failed to get system-specific file info: not running on linux")
+	}
+
+	return int(linuxFileInfo.Uid), int(linuxFileInfo.Gid)
+}
+
+// overwriteFile writes the data to the file in place, keeping its inode (so
+// the active fsnotify watches stay valid), mode and ownership. The file is
+// created with the mode if it does not exist.
+func overwriteFile(path string, data []byte, mode os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// permissionBits strips the type bits (e.g., os.ModeDir) from the mode,
+// leaving only the bits that can be changed with os.Chmod.
+func permissionBits(mode os.FileMode) os.FileMode {
+	return mode & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+}
+
 func lookupUser(name string) (int, error) {
 	user, err := user.Lookup(name)
 	if err != nil {
@@ -51,37 +100,3 @@ func lookupGid(gid int) (string, error) {
 
 	return group.Name, nil
 }
-
-func lookupUserDetails(name string) (int, int, []int, error) {
-	user, err := user.Lookup(name)
-	if err != nil {
-		return 0, 0, nil, fmt.Errorf("failed to lookup user by name: %w", err)
-	}
-
-	uid, err := strconv.Atoi(user.Uid)
-	if err != nil {
-		return 0, 0, nil, fmt.Errorf("failed to parse uid: %w", err)
-	}
-
-	gid, err := strconv.Atoi(user.Gid)
-	if err != nil {
-		return 0, 0, nil, fmt.Errorf("failed to parse gid: %w", err)
-	}
-
-	groupIdsStr, err := user.GroupIds()
-	if err != nil {
-		return 0, 0, nil, fmt.Errorf("failed to get group ids: %w", err)
-	}
-
-	groupIds := make([]int, 0, len(groupIdsStr))
-	for _, groupIdStr := range groupIdsStr {
-		groupId, err := strconv.Atoi(groupIdStr)
-		if err != nil {
-			return 0, 0, nil, fmt.Errorf("failed to parse group's id: %w", err)
-		}
-
-		groupIds = append(groupIds, groupId)
-	}
-
-	return uid, gid, groupIds, nil
-}
@@ -0,0 +1,197 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/scherepiuk/align/internal/logger"
+	"github.com/scherepiuk/align/internal/types"
+)
+
+const defaultConfigKeyMode = os.FileMode(0o644)
+
+type ConfigFormat int
+
+const (
+	ConfigFormatJSON ConfigFormat = iota
+	ConfigFormatYAML
+	ConfigFormatTOML
+	ConfigFormatINI
+)
+
+func (f ConfigFormat) String() string {
+	switch f {
+	case ConfigFormatJSON:
+		return "json"
+	case ConfigFormatYAML:
+		return "yaml"
+	case ConfigFormatTOML:
+		return "toml"
+	case ConfigFormatINI:
+		return "ini"
+	default:
+		return "unknown"
+	}
+}
+
+// ConfigKey manages a single key of a structured config file. Values are
+// compared after parsing, so formatting differences are not reported, and
+// only the managed key is rewritten when the correction is applied.
+type ConfigKey struct {
+	BaseDependant
+	path   string
+	key    []string
+	value  any
+	format types.Optional[ConfigFormat]
+	backup types.Optional[*Backup]
+}
+
+// NewConfigKey creates the resource for the key path (e.g.,
+// "server.tls.min_version"). The format is inferred from the extension of
+// the file, unless it is specified with WithConfigFormat.
+func NewConfigKey(path, key string, value any, opts ...ConfigKeyOption) *ConfigKey {
+	configKey := &ConfigKey{path: path, key: strings.Split(key, "."), value: value}
+
+	for _, opt := range opts {
+		opt(configKey)
+	}
+
+	return configKey
+}
+
+type ConfigKeyOption func(configKey *ConfigKey)
+
+func WithConfigFormat(format ConfigFormat) ConfigKeyOption {
+	return func(configKey *ConfigKey) {
+		logger.Global().Info("specifying config format", "path", configKey.path, "format", format)
+		configKey.format = types.NewOptional(format)
+	}
+}
+
+// WithConfigKeyBackup saves the file before the value is changed.
+func WithConfigKeyBackup(backup *Backup) ConfigKeyOption {
+	return func(configKey *ConfigKey) {
+		logger.Global().Info("specifying config backup", "path", configKey.path, "dir", backup.dir)
+		configKey.backup = types.NewOptional(backup)
+	}
+}
+
+func (c *ConfigKey) Id() string {
+	return fmt.Sprintf("%s:%s", c.path, strings.Join(c.key, "."))
+}
+
+func (c *ConfigKey) Check() ([]Correction, error) {
+	codec, err := c.codec()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(c.path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Global().Warn("config file does not exist", "path", c.path)
+		return []Correction{c.changeValue}, ErrUnalignedResource
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	actual, ok, err := codec.get(data, c.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config value: %w", err)
+	}
+
+	if !ok {
+		logger.Global().Warn("config key is missing", "path", c.path, "key", strings.Join(c.key, "."))
+		return []Correction{c.changeValue}, ErrUnalignedResource
+	}
+
+	target, err := codec.normalize(c.value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize config value: %w", err)
+	}
+
+	if !reflect.DeepEqual(actual, target) {
+		logger.Global().Warn(
+			"config key has wrong value", "path", c.path, "key", strings.Join(c.key, "."),
+			"value.actual", actual, "value.target", target,
+		)
+		return []Correction{c.changeValue}, ErrUnalignedResource
+	}
+
+	return nil, nil
+}
+
+func (c *ConfigKey) Watch(
+	ctx context.Context,
+	correctionsCh chan<- []Correction,
+	errCh chan<- error,
+) {
+	watchFile(ctx, c, fileWatch{path: c.path}, correctionsCh, errCh)
+}
+
+func (c *ConfigKey) codec() (configCodec, error) {
+	format := c.format
+	if !format.Ok() {
+		switch strings.ToLower(filepath.Ext(c.path)) {
+		case ".json":
+			format = types.NewOptional(ConfigFormatJSON)
+		case ".yaml", ".yml":
+			format = types.NewOptional(ConfigFormatYAML)
+		case ".toml":
+			format = types.NewOptional(ConfigFormatTOML)
+		case ".ini", ".conf", ".cfg":
+			format = types.NewOptional(ConfigFormatINI)
+		default:
+			return nil, fmt.Errorf("failed to infer config format: %s", c.path)
+		}
+	}
+
+	switch format.Value() {
+	case ConfigFormatJSON:
+		return jsonCodec{}, nil
+	case ConfigFormatYAML:
+		return yamlCodec{}, nil
+	case ConfigFormatTOML:
+		return tomlCodec{}, nil
+	case ConfigFormatINI:
+		return iniCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported config format: %s", format.Value())
+	}
+}
+
+func (c *ConfigKey) changeValue() error {
+	codec, err := c.codec()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	data, err = codec.set(data, c.key, c.value)
+	if err != nil {
+		return fmt.Errorf("failed to set config value: %w", err)
+	}
+
+	err = saveBackup(c.backup, c.Id(), c.path)
+	if err != nil {
+		return err
+	}
+
+	err = overwriteFile(c.path, data, defaultConfigKeyMode)
+	if err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return nil
+}
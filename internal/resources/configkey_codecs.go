@@ -0,0 +1,537 @@
+package resources
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+type configCodec interface {
+	// get returns the normalized value at the key path.
+	get(data []byte, key []string) (any, bool, error)
+
+	// set returns the data with the value at the key path replaced, leaving
+	// the rest of the document intact as far as the format allows.
+	set(data []byte, key []string, value any) ([]byte, error)
+
+	// normalize converts the value to the form returned by get.
+	normalize(value any) (any, error)
+}
+
+// normalizeJSON round-trips the value through JSON, so that values decoded by
+// different libraries (e.g., int vs. int64 vs. float64) compare equal.
+func normalizeJSON(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	var normalized any
+	err = json.Unmarshal(data, &normalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+
+	return normalized, nil
+}
+
+func lookupKey(document any, key []string) (any, bool) {
+	for _, name := range key {
+		table, ok := document.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		document, ok = table[name]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return document, true
+}
+
+func setKey(document map[string]any, key []string, value any) error {
+	for _, name := range key[:len(key)-1] {
+		next, ok := document[name]
+		if !ok {
+			table := make(map[string]any)
+			document[name] = table
+			document = table
+			continue
+		}
+
+		table, ok := next.(map[string]any)
+		if !ok {
+			return fmt.Errorf("key is not a table: %s", name)
+		}
+
+		document = table
+	}
+
+	document[key[len(key)-1]] = value
+	return nil
+}
+
+// jsonCodec splices the new value into the original document when the key
+// exists. Otherwise the whole document is re-encoded, which sorts its keys.
+type jsonCodec struct{}
+
+func (jsonCodec) get(data []byte, key []string) (any, bool, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, false, nil
+	}
+
+	var document any
+	err := json.Unmarshal(data, &document)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal json: %w", err)
+	}
+
+	value, ok := lookupKey(document, key)
+	return value, ok, nil
+}
+
+func (jsonCodec) set(data []byte, key []string, value any) ([]byte, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal json: %w", err)
+	}
+
+	start, end, ok := jsonValueSpan(data, key)
+	if ok {
+		spliced := make([]byte, 0, len(data)-(end-start)+len(encoded))
+		spliced = append(spliced, data[:start]...)
+		spliced = append(spliced, encoded...)
+		spliced = append(spliced, data[end:]...)
+		return spliced, nil
+	}
+
+	document := make(map[string]any)
+	if len(bytes.TrimSpace(data)) > 0 {
+		err = json.Unmarshal(data, &document)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal json: %w", err)
+		}
+	}
+
+	err = setKey(document, key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err = json.MarshalIndent(document, "", detectIndent(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal json: %w", err)
+	}
+
+	return append(encoded, '\n'), nil
+}
+
+func (jsonCodec) normalize(value any) (any, error) {
+	return normalizeJSON(value)
+}
+
+// jsonValueSpan returns the offsets of the value at the key path.
+func jsonValueSpan(data []byte, key []string) (int, int, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	for i, name := range key {
+		token, err := decoder.Token()
+		if err != nil {
+			return 0, 0, false
+		}
+
+		if delim, ok := token.(json.Delim); !ok || delim != '{' {
+			return 0, 0, false
+		}
+
+		found := false
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return 0, 0, false
+			}
+
+			if token == name {
+				found = true
+				break
+			}
+
+			var skipped json.RawMessage
+			err = decoder.Decode(&skipped)
+			if err != nil {
+				return 0, 0, false
+			}
+		}
+
+		if !found {
+			return 0, 0, false
+		}
+
+		if i == len(key)-1 {
+			var raw json.RawMessage
+			err = decoder.Decode(&raw)
+			if err != nil {
+				return 0, 0, false
+			}
+
+			end := int(decoder.InputOffset())
+			return end - len(raw), end, true
+		}
+	}
+
+	return 0, 0, false
+}
+
+var indentRegexp = regexp.MustCompile(`\n([ \t]+)\S`)
+
+func detectIndent(data []byte) string {
+	match := indentRegexp.FindSubmatch(data)
+	if match == nil {
+		return "  "
+	}
+
+	return string(match[1])
+}
+
+// yamlCodec edits the node tree of the document, which keeps the comments
+// and the order of the keys, although the indentation is normalized.
+type yamlCodec struct{}
+
+func (yamlCodec) get(data []byte, key []string) (any, bool, error) {
+	var document yaml.Node
+	err := yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal yaml: %w", err)
+	}
+
+	if len(document.Content) == 0 {
+		return nil, false, nil
+	}
+
+	node := document.Content[0]
+	for _, name := range key {
+		node = yamlChild(node, name)
+		if node == nil {
+			return nil, false, nil
+		}
+	}
+
+	var value any
+	err = node.Decode(&value)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode yaml: %w", err)
+	}
+
+	value, err = normalizeJSON(value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+func (yamlCodec) set(data []byte, key []string, value any) ([]byte, error) {
+	var document yaml.Node
+	err := yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal yaml: %w", err)
+	}
+
+	if len(document.Content) == 0 {
+		document = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+
+	node := document.Content[0]
+	for _, name := range key {
+		if node.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("key is not a mapping: %s", name)
+		}
+
+		child := yamlChild(node, name)
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(
+				node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name},
+				child,
+			)
+		}
+
+		node = child
+	}
+
+	var encoded yaml.Node
+	err = encoded.Encode(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode yaml: %w", err)
+	}
+
+	encoded.HeadComment = node.HeadComment
+	encoded.LineComment = node.LineComment
+	encoded.FootComment = node.FootComment
+	*node = encoded
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+
+	err = encoder.Encode(&document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal yaml: %w", err)
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal yaml: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (yamlCodec) normalize(value any) (any, error) {
+	return normalizeJSON(value)
+}
+
+func yamlChild(node *yaml.Node, name string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == name {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// tomlCodec replaces the line of the key when it is a simple single-line
+// assignment. Otherwise the whole document is re-encoded, dropping comments.
+type tomlCodec struct{}
+
+func (tomlCodec) get(data []byte, key []string) (any, bool, error) {
+	document := make(map[string]any)
+	err := toml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal toml: %w", err)
+	}
+
+	value, ok := lookupKey(document, key)
+	if !ok {
+		return nil, false, nil
+	}
+
+	value, err = normalizeJSON(value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+func (tomlCodec) set(data []byte, key []string, value any) ([]byte, error) {
+	spliced, ok := spliceTOML(data, key, value)
+	if ok {
+		return spliced, nil
+	}
+
+	document := make(map[string]any)
+	err := toml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal toml: %w", err)
+	}
+
+	err = setKey(document, key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	err = toml.NewEncoder(&buffer).Encode(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal toml: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (tomlCodec) normalize(value any) (any, error) {
+	return normalizeJSON(value)
+}
+
+var (
+	tomlTableRegexp = regexp.MustCompile(`^\s*\[\s*([A-Za-z0-9_.-]+)\s*\]\s*(#.*)?$`)
+	tomlKeyRegexp   = regexp.MustCompile(`^(\s*)([A-Za-z0-9_-]+)(\s*=\s*)(.*)$`)
+)
+
+func spliceTOML(data []byte, key []string, value any) ([]byte, bool) {
+	kind := reflect.ValueOf(value).Kind()
+	if kind == reflect.Map || kind == reflect.Struct {
+		return nil, false
+	}
+
+	encoded, err := toml.Marshal(map[string]any{"v": value})
+	if err != nil {
+		return nil, false
+	}
+
+	literal := strings.TrimSuffix(strings.TrimPrefix(string(encoded), "v = "), "\n")
+	if strings.Contains(literal, "\n") {
+		return nil, false
+	}
+
+	var (
+		lines  = strings.Split(string(data), "\n")
+		target = strings.Join(key, ".")
+		table  = ""
+	)
+
+	for i, line := range lines {
+		if match := tomlTableRegexp.FindStringSubmatch(line); match != nil {
+			table = match[1]
+			continue
+		}
+
+		// NOTE: Arrays of tables and quoted table names are not supported.
+		if strings.HasPrefix(strings.TrimSpace(line), "[") {
+			table = "\x00"
+			continue
+		}
+
+		match := tomlKeyRegexp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		name := match[2]
+		if table != "" {
+			name = fmt.Sprintf("%s.%s", table, match[2])
+		}
+
+		if name != target {
+			continue
+		}
+
+		// NOTE: Values spanning multiple lines can't be replaced line-wise.
+		var parsed map[string]any
+		if toml.Unmarshal([]byte(strings.TrimSpace(line)), &parsed) != nil {
+			return nil, false
+		}
+
+		lines[i] = match[1] + match[2] + match[3] + literal
+		return []byte(strings.Join(lines, "\n")), true
+	}
+
+	return nil, false
+}
+
+// iniCodec works line-wise, so the document is always kept intact. The last
+// element of the key path is the key, the preceding ones form the section
+// name. Keys outside of any section are addressed by a single-element path.
+type iniCodec struct{}
+
+var (
+	iniSectionRegexp = regexp.MustCompile(`^\s*\[\s*([^\]]+?)\s*\]\s*$`)
+	iniKeyRegexp     = regexp.MustCompile(`^(\s*)([^=:;#\[\s][^=:]*?)(\s*[=:]\s*)(.*?)\s*$`)
+)
+
+func (iniCodec) get(data []byte, key []string) (any, bool, error) {
+	section, name := iniLocation(key)
+
+	current := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		if match := iniSectionRegexp.FindStringSubmatch(line); match != nil {
+			current = match[1]
+			continue
+		}
+
+		match := iniKeyRegexp.FindStringSubmatch(line)
+		if match != nil && current == section && match[2] == name {
+			return match[4], true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+func (iniCodec) set(data []byte, key []string, value any) ([]byte, error) {
+	section, name := iniLocation(key)
+
+	lines := []string{}
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+
+	var (
+		current  = ""
+		found    = section == ""
+		insertAt = -1
+	)
+
+	if section == "" {
+		insertAt = 0
+	}
+
+	for i, line := range lines {
+		if match := iniSectionRegexp.FindStringSubmatch(line); match != nil {
+			current = match[1]
+			if current == section {
+				found, insertAt = true, i+1
+			}
+			continue
+		}
+
+		if current != section {
+			continue
+		}
+
+		match := iniKeyRegexp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		if match[2] == name {
+			lines[i] = fmt.Sprintf("%s%s%s%v", match[1], match[2], match[3], value)
+			return []byte(strings.Join(lines, "\n") + "\n"), nil
+		}
+
+		insertAt = i + 1
+	}
+
+	entry := fmt.Sprintf("%s = %v", name, value)
+
+	if !found {
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+			lines = append(lines, "")
+		}
+		lines = append(lines, fmt.Sprintf("[%s]", section), entry)
+	} else {
+		lines = append(lines[:insertAt], append([]string{entry}, lines[insertAt:]...)...)
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+func (iniCodec) normalize(value any) (any, error) {
+	return fmt.Sprint(value), nil
+}
+
+func iniLocation(key []string) (string, string) {
+	if len(key) == 1 {
+		return "", key[0]
+	}
+
+	return strings.Join(key[:len(key)-1], "."), key[len(key)-1]
+}
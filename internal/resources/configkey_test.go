@@ -0,0 +1,178 @@
+package resources
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigCodecSetUnit(t *testing.T) {
+	type testCase struct {
+		name     string
+		codec    configCodec
+		data     string
+		key      string
+		value    any
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "json existing key is spliced",
+			codec:    jsonCodec{},
+			data:     "{\n    \"b\": 1,\n    \"a\": {\"x\": \"old\", \"y\": [1, 2]}\n}\n",
+			key:      "a.x",
+			value:    "new",
+			expected: "{\n    \"b\": 1,\n    \"a\": {\"x\": \"new\", \"y\": [1, 2]}\n}\n",
+		},
+		{
+			name:     "json missing key is added",
+			codec:    jsonCodec{},
+			data:     "{\n    \"b\": 1\n}\n",
+			key:      "a.x",
+			value:    true,
+			expected: "{\n    \"a\": {\n        \"x\": true\n    },\n    \"b\": 1\n}\n",
+		},
+		{
+			name:     "json empty document",
+			codec:    jsonCodec{},
+			data:     "",
+			key:      "a",
+			value:    1,
+			expected: "{\n  \"a\": 1\n}\n",
+		},
+		{
+			name:     "yaml existing key keeps comments",
+			codec:    yamlCodec{},
+			data:     "# server settings\nserver:\n  port: 80 # public port\n  host: localhost\n",
+			key:      "server.port",
+			value:    8080,
+			expected: "# server settings\nserver:\n  port: 8080 # public port\n  host: localhost\n",
+		},
+		{
+			name:     "yaml missing key is added",
+			codec:    yamlCodec{},
+			data:     "server:\n  host: localhost\n",
+			key:      "server.tls.min_version",
+			value:    "1.2",
+			expected: "server:\n  host: localhost\n  tls:\n    min_version: \"1.2\"\n",
+		},
+		{
+			name:     "toml existing key is replaced line-wise",
+			codec:    tomlCodec{},
+			data:     "# comment\ntitle = \"app\"\n\n[server.tls]\nmin_version = \"1.0\"\nciphers = [\"a\"]\n",
+			key:      "server.tls.min_version",
+			value:    "1.2",
+			expected: "# comment\ntitle = \"app\"\n\n[server.tls]\nmin_version = \"1.2\"\nciphers = [\"a\"]\n",
+		},
+		{
+			name:     "toml missing key is added",
+			codec:    tomlCodec{},
+			data:     "title = \"app\"\n",
+			key:      "server.port",
+			value:    8080,
+			expected: "title = \"app\"\n\n[server]\n  port = 8080\n",
+		},
+		{
+			name:     "ini existing key is replaced",
+			codec:    iniCodec{},
+			data:     "; comment\nglobal=1\n\n[server]\nport = 80\nhost = localhost\n",
+			key:      "server.port",
+			value:    8080,
+			expected: "; comment\nglobal=1\n\n[server]\nport = 8080\nhost = localhost\n",
+		},
+		{
+			name:     "ini missing key is added to section",
+			codec:    iniCodec{},
+			data:     "[server]\nport = 80\n\n[client]\nretries = 3\n",
+			key:      "server.host",
+			value:    "localhost",
+			expected: "[server]\nport = 80\nhost = localhost\n\n[client]\nretries = 3\n",
+		},
+		{
+			name:     "ini missing section is appended",
+			codec:    iniCodec{},
+			data:     "global = 1\n",
+			key:      "server.port",
+			value:    80,
+			expected: "global = 1\n\n[server]\nport = 80\n",
+		},
+		{
+			name:     "ini missing global key is added",
+			codec:    iniCodec{},
+			data:     "global = 1\n\n[server]\nport = 80\n",
+			key:      "debug",
+			value:    false,
+			expected: "global = 1\ndebug = false\n\n[server]\nport = 80\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key := strings.Split(tc.key, ".")
+
+			actual, err := tc.codec.set([]byte(tc.data), key, tc.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(actual))
+
+			value, ok, err := tc.codec.get(actual, key)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+
+			expected, err := tc.codec.normalize(tc.value)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, value)
+		})
+	}
+}
+
+func TestConfigKeyCheckIntegration(t *testing.T) {
+	t.Run("config key has wrong value", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+
+		err := os.WriteFile(path, []byte("server:\n  tls:\n    min_version: \"1.0\"\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		configKey := NewConfigKey(path, "server.tls.min_version", "1.2")
+		expected := []Correction{configKey.changeValue}
+
+		actual, err := configKey.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		err = configKey.changeValue()
+		assert.NoError(t, err)
+
+		corrections, err := configKey.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("config key value differs only in formatting", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+
+		err := os.WriteFile(path, []byte(`{"server": {"ports": [ 80,443 ]}}`), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		configKey := NewConfigKey(path, "server.ports", []int{80, 443})
+
+		corrections, err := configKey.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("config format can't be inferred", func(t *testing.T) {
+		configKey := NewConfigKey("/tmp/config", "key", "value")
+
+		corrections, err := configKey.Check()
+		assert.Nil(t, corrections)
+		assert.ErrorContains(t, err, "failed to infer config format")
+	})
+}
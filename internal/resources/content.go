@@ -0,0 +1,120 @@
+package resources
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/scherepiuk/align/internal/facts"
+	"github.com/scherepiuk/align/internal/utils"
+)
+
+// fileContent describes the desired content of a file. Only the checksum is
+// used by checks, the content itself is streamed from its source when the
+// correction is applied, so it doesn't have to be kept in memory (except for
+// templates, see newTemplateContent).
+type fileContent struct {
+	checksum func() (string, error)
+	open     func() (io.ReadCloser, error)
+	sources  []string // files the content is produced from
+}
+
+func newStaticContent(content string) fileContent {
+	checksum, _ := utils.Checksum(strings.NewReader(content))
+
+	return fileContent{
+		checksum: func() (string, error) {
+			return checksum, nil
+		},
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(content)), nil
+		},
+	}
+}
+
+func newSourceContent(source string) fileContent {
+	return fileContent{
+		checksum: func() (string, error) {
+			checksum, err := utils.ChecksumFile(source)
+			if err != nil {
+				return "", fmt.Errorf("failed to compute source's checksum: %w", err)
+			}
+
+			return checksum, nil
+		},
+		open: func() (io.ReadCloser, error) {
+			file, err := os.Open(source)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open source: %w", err)
+			}
+
+			return file, nil
+		},
+		sources: []string{source},
+	}
+}
+
+// templateData is what templates are executed with, variables are accessible
+// as {{ .Vars.name }} and host facts as {{ .Facts.Hostname }}.
+type templateData struct {
+	Vars  map[string]any
+	Facts facts.Facts
+}
+
+// newTemplateContent renders the template on every use, so the changes to the
+// template itself, and to the host facts are always taken into account. The
+// content is rendered in full before it is opened, so a template failing
+// midway leaves the file alone instead of writing a part of it.
+func newTemplateContent(
+	parse func() (*template.Template, error),
+	vars map[string]any,
+	sources ...string,
+) fileContent {
+	render := func(w io.Writer) error {
+		tmpl, err := parse()
+		if err != nil {
+			return fmt.Errorf("failed to parse template: %w", err)
+		}
+
+		facts, err := facts.Gather()
+		if err != nil {
+			return fmt.Errorf("failed to gather facts: %w", err)
+		}
+
+		err = tmpl.Execute(w, templateData{Vars: vars, Facts: facts})
+		if err != nil {
+			return fmt.Errorf("failed to execute template: %w", err)
+		}
+
+		return nil
+	}
+
+	return fileContent{
+		checksum: func() (string, error) {
+			hash := sha256.New()
+
+			err := render(hash)
+			if err != nil {
+				return "", err
+			}
+
+			return hex.EncodeToString(hash.Sum(nil)), nil
+		},
+		open: func() (io.ReadCloser, error) {
+			var content bytes.Buffer
+
+			err := render(&content)
+			if err != nil {
+				return nil, err
+			}
+
+			return io.NopCloser(&content), nil
+		},
+		sources: sources,
+	}
+}
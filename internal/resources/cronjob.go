@@ -0,0 +1,595 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/scherepiuk/align/internal/logger"
+	"github.com/scherepiuk/align/internal/types"
+	"github.com/scherepiuk/align/internal/utils"
+)
+
+const (
+	defaultCronDir     = "/etc/cron.d"
+	defaultCronFile    = "align"
+	defaultCronUser    = "root"
+	defaultCronPolling = 5 * time.Second
+	cronMarkerPrefix   = "# align: "
+)
+
+var (
+	cronNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	cronEnvRegexp  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	cronShortcuts = []string{"@reboot", "@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+	cronFields    = []cronField{
+		{name: "minute", min: 0, max: 59},
+		{name: "hour", min: 0, max: 23},
+		{name: "day of month", min: 1, max: 31},
+		{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+		{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+	}
+)
+
+// CronJob manages a single entry, either in a file in /etc/cron.d (by
+// default) or in the crontab of its user (see WithUserCrontab). The entry is
+// marked with a "# align: <name>" comment right above it, and the rest of the
+// file is left alone.
+type CronJob struct {
+	BaseDependant
+	name     string
+	schedule string
+	command  string
+	user     string
+	env      map[string]string
+	ensure   Ensure
+
+	dir     string
+	file    string
+	crontab bool
+	backup  types.Optional[*Backup]
+
+	runner       CommandRunner
+	pollInterval time.Duration
+}
+
+// NewCronJob returns an error if the name or the schedule is invalid, so a
+// broken entry is never installed.
+func NewCronJob(name, schedule, command string, opts ...CronJobOption) (*CronJob, error) {
+	job := &CronJob{
+		name:         name,
+		schedule:     schedule,
+		command:      command,
+		user:         defaultCronUser,
+		env:          make(map[string]string),
+		dir:          defaultCronDir,
+		file:         defaultCronFile,
+		runner:       NewExecRunner(),
+		pollInterval: defaultCronPolling,
+	}
+
+	for _, opt := range opts {
+		opt(job)
+	}
+
+	if !cronNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid cron job name: %q", name)
+	}
+
+	err := validateCronSchedule(schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron schedule of %s: %w", name, err)
+	}
+
+	// NOTE: The user is a field of the entry, and an argument of crontab.
+	if job.user == "" || strings.ContainsAny(job.user, " \t\n") {
+		return nil, fmt.Errorf("invalid cron user of %s: %q", name, job.user)
+	}
+
+	if command == "" || strings.Contains(command, "\n") {
+		return nil, fmt.Errorf("invalid cron command of %s: %q", name, command)
+	}
+
+	for key, value := range job.env {
+		if !cronEnvRegexp.MatchString(key) || strings.Contains(value, "\n") {
+			return nil, fmt.Errorf("invalid cron environment variable of %s: %q", name, key)
+		}
+	}
+
+	// NOTE: cron skips the files in /etc/cron.d with dots in their names.
+	if !job.crontab && !cronNameRegexp.MatchString(job.file) {
+		return nil, fmt.Errorf("cron file would be ignored by cron: %s", job.file)
+	}
+
+	return job, nil
+}
+
+type CronJobOption func(job *CronJob)
+
+// WithCronUser changes the user the job runs as, which is root by default.
+func WithCronUser(user string) CronJobOption {
+	return func(job *CronJob) {
+		logger.Global().Info("specifying cron job user", "name", job.name, "user", user)
+		job.user = user
+	}
+}
+
+func WithCronEnv(key, value string) CronJobOption {
+	return func(job *CronJob) {
+		logger.Global().Info("specifying cron job environment variable", "name", job.name, "key", key)
+		job.env[key] = value
+	}
+}
+
+// WithCronFile changes the file in /etc/cron.d the entry is kept in.
+func WithCronFile(file string) CronJobOption {
+	return func(job *CronJob) {
+		logger.Global().Info("specifying cron job file", "name", job.name, "file", file)
+		job.file = file
+	}
+}
+
+// WithCronDir changes the directory of the file the entry is kept in.
+func WithCronDir(dir string) CronJobOption {
+	return func(job *CronJob) {
+		logger.Global().Info("specifying cron job directory", "name", job.name, "dir", dir)
+		job.dir = dir
+	}
+}
+
+// WithUserCrontab keeps the entry in the crontab of the user (see
+// WithCronUser) instead, which is read and written with crontab(1).
+func WithUserCrontab() CronJobOption {
+	return func(job *CronJob) {
+		logger.Global().Info("specifying cron job in user crontab", "name", job.name)
+		job.crontab = true
+	}
+}
+
+func WithCronEnsure(ensure Ensure) CronJobOption {
+	return func(job *CronJob) {
+		logger.Global().Info("specifying cron job ensure", "name", job.name, "ensure", ensure)
+		job.ensure = ensure
+	}
+}
+
+// WithCronBackup saves the file or the crontab before the job is installed or
+// removed.
+func WithCronBackup(backup *Backup) CronJobOption {
+	return func(job *CronJob) {
+		logger.Global().Info("specifying cron backup", "name", job.name, "dir", backup.dir)
+		job.backup = types.NewOptional(backup)
+	}
+}
+
+// WithCronRunner changes how crontab is run, the commands are executed on
+// the host by default.
+func WithCronRunner(runner CommandRunner) CronJobOption {
+	return func(job *CronJob) {
+		logger.Global().Info("specifying cron job runner", "name", job.name, "runner", fmt.Sprintf("%T", runner))
+		job.runner = runner
+	}
+}
+
+func (c *CronJob) Id() string {
+	return "cron:" + c.name
+}
+
+func (c *CronJob) Check() ([]Correction, error) {
+	lines, err := c.readEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	i := slices.Index(lines, c.marker())
+
+	if c.ensure == EnsureAbsent {
+		if i == -1 {
+			return nil, nil
+		}
+
+		logger.Global().Warn("cron job exists but should be absent", "name", c.name)
+		return []Correction{c.remove}, ErrUnalignedResource
+	}
+
+	if i == -1 {
+		logger.Global().Warn("cron job does not exist", "name", c.name)
+		return []Correction{c.install}, ErrUnalignedResource
+	}
+
+	if i+1 >= len(lines) || lines[i+1] != c.entry() {
+		logger.Global().Warn("cron job has wrong entry", "name", c.name)
+		return []Correction{c.install}, ErrUnalignedResource
+	}
+
+	return nil, nil
+}
+
+// Watch observes the directory of the file, which cron-related tools tend to
+// replace rather than write in place. The crontabs are kept in a spool owned
+// by cron, so they are polled instead.
+func (c *CronJob) Watch(
+	ctx context.Context,
+	correctionsCh chan<- []Correction,
+	errCh chan<- error,
+) {
+	err := check(c, correctionsCh)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	var (
+		eventsCh <-chan fsnotify.Event
+		errorsCh <-chan error
+		pollCh   <-chan time.Time
+	)
+
+	if c.crontab {
+		ticker := time.NewTicker(c.pollInterval)
+		defer ticker.Stop()
+		pollCh = ticker.C
+	} else {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			errCh <- err
+			return
+		}
+		defer watcher.Close()
+
+		err = utils.Retry(
+			ctx,
+			func() error { return watcher.Add(c.dir) },
+			100*time.Millisecond,
+			os.ErrNotExist,
+		)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		eventsCh, errorsCh = watcher.Events, watcher.Errors
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			errCh <- ctx.Err()
+			return
+
+		case err := <-errorsCh:
+			errCh <- err
+			return
+
+		case event := <-eventsCh:
+			if event.Name != c.path() {
+				continue
+			}
+
+			logger.Global().Debug("got fsnotify event", "event", event.String())
+
+			err := check(c, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+		case <-pollCh:
+			err := check(c, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}
+}
+
+func (c *CronJob) path() string {
+	return filepath.Join(c.dir, c.file)
+}
+
+func (c *CronJob) marker() string {
+	return cronMarkerPrefix + c.name
+}
+
+// entry renders the line of the job. The environment is exported by the
+// command itself, since the variables set in the file would apply to the
+// entries of other tools as well.
+func (c *CronJob) entry() string {
+	keys := make([]string, 0, len(c.env))
+	for key := range c.env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	command := ""
+	for _, key := range keys {
+		command += fmt.Sprintf("export %s=%s; ", key, shellQuote(c.env[key]))
+	}
+	command += c.command
+
+	// NOTE: An unescaped percent sign is a newline for cron.
+	command = strings.ReplaceAll(command, "%", `\%`)
+
+	if c.crontab {
+		return fmt.Sprintf("%s %s", c.schedule, command)
+	}
+
+	return fmt.Sprintf("%s %s %s", c.schedule, c.user, command)
+}
+
+func (c *CronJob) install() error {
+	lines, err := c.readEntries()
+	if err != nil {
+		return err
+	}
+
+	i := slices.Index(lines, c.marker())
+
+	switch {
+	case i == -1:
+		lines = append(lines, c.marker(), c.entry())
+	case i+1 < len(lines) && c.isEntry(lines[i+1]):
+		lines[i+1] = c.entry()
+	default:
+		lines = slices.Insert(lines, i+1, c.entry())
+	}
+
+	err = c.writeEntries(lines)
+	if err != nil {
+		return fmt.Errorf("failed to install cron job: %w", err)
+	}
+
+	return nil
+}
+
+func (c *CronJob) remove() error {
+	lines, err := c.readEntries()
+	if err != nil {
+		return err
+	}
+
+	i := slices.Index(lines, c.marker())
+	if i == -1 {
+		return nil
+	}
+
+	end := i + 1
+	if end < len(lines) && c.isEntry(lines[end]) {
+		end++
+	}
+
+	err = c.writeEntries(slices.Delete(lines, i, end))
+	if err != nil {
+		return fmt.Errorf("failed to remove cron job: %w", err)
+	}
+
+	return nil
+}
+
+// isEntry tells whether the line is a cron entry, which the line after the
+// marker has to be to be replaced or removed with it. Anything else (e.g., an
+// environment variable, or a comment) is not ours and is left alone.
+func (c *CronJob) isEntry(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return false
+	}
+
+	scheduleFields := len(cronFields)
+	if strings.HasPrefix(fields[0], "@") {
+		if !slices.Contains(cronShortcuts, fields[0]) {
+			return false
+		}
+		scheduleFields = 1
+	}
+
+	// NOTE: The entries of the files in /etc/cron.d have the user field.
+	minFields := scheduleFields + 1
+	if !c.crontab {
+		minFields++
+	}
+
+	if len(fields) < minFields {
+		return false
+	}
+
+	if scheduleFields == len(cronFields) {
+		for i, field := range cronFields {
+			if field.validate(fields[i]) != nil {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// readEntries returns the lines of the file or the crontab, a missing one is
+// empty.
+func (c *CronJob) readEntries() ([]string, error) {
+	if !c.crontab {
+		lines, err := readLines(c.path())
+		if errors.Is(err, os.ErrNotExist) {
+			return []string{}, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cron file: %w", err)
+		}
+
+		return lines, nil
+	}
+
+	result, err := c.runner.Run(context.Background(), Command{Name: "crontab", Args: []string{"-l", "-u", c.user}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read crontab: %w", err)
+	}
+
+	if result.ExitCode != 0 {
+		if strings.Contains(result.output(), "no crontab for") {
+			return []string{}, nil
+		}
+
+		return nil, fmt.Errorf("failed to read crontab: exit status %d: %s", result.ExitCode, result.output())
+	}
+
+	content := strings.TrimSuffix(string(result.Stdout), "\n")
+	if content == "" {
+		return []string{}, nil
+	}
+
+	return strings.Split(content, "\n"), nil
+}
+
+func (c *CronJob) writeEntries(lines []string) error {
+	content := ""
+	if len(lines) > 0 {
+		content = strings.Join(lines, "\n") + "\n"
+	}
+
+	err := c.saveBackup()
+	if err != nil {
+		return err
+	}
+
+	if !c.crontab {
+		return overwriteFile(c.path(), []byte(content), defaultLineInFileMode)
+	}
+
+	_, err = runChecked(
+		context.Background(),
+		c.runner,
+		Command{Name: "crontab", Args: []string{"-u", c.user, "-"}, Stdin: []byte(content)},
+	)
+
+	return err
+}
+
+// saveBackup saves the file, or the current content of the crontab, which is
+// not a file align can copy.
+func (c *CronJob) saveBackup() error {
+	if !c.backup.Ok() {
+		return nil
+	}
+
+	if !c.crontab {
+		return saveBackup(c.backup, c.Id(), c.path())
+	}
+
+	lines, err := c.readEntries()
+	if err != nil {
+		return err
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	err = c.backup.Value().SaveContent(c.Id(), []byte(strings.Join(lines, "\n")+"\n"))
+	if err != nil {
+		return fmt.Errorf("failed to back up crontab: %w", err)
+	}
+
+	return nil
+}
+
+// shellQuote quotes the value for sh.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names []string
+}
+
+func validateCronSchedule(schedule string) error {
+	if slices.Contains(cronShortcuts, schedule) {
+		return nil
+	}
+
+	fields := strings.Fields(schedule)
+	if len(fields) != len(cronFields) || strings.Join(fields, " ") != schedule {
+		return fmt.Errorf("expected %d fields separated by single spaces: %q", len(cronFields), schedule)
+	}
+
+	for i, field := range fields {
+		err := cronFields[i].validate(field)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", cronFields[i].name, err)
+		}
+	}
+
+	return nil
+}
+
+// validate checks a list of values, ranges (optionally with steps) or
+// asterisks (optionally with steps).
+func (f cronField) validate(field string) error {
+	for _, item := range strings.Split(field, ",") {
+		span, step, stepped := strings.Cut(item, "/")
+
+		if stepped {
+			n, err := strconv.Atoi(step)
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid step: %q", item)
+			}
+		}
+
+		if span == "*" {
+			continue
+		}
+
+		low, high, ranged := strings.Cut(span, "-")
+
+		first, err := f.value(low)
+		if err != nil {
+			return err
+		}
+
+		if !ranged {
+			if stepped {
+				return fmt.Errorf("step without a range: %q", item)
+			}
+			continue
+		}
+
+		last, err := f.value(high)
+		if err != nil {
+			return err
+		}
+
+		if last < first {
+			return fmt.Errorf("invalid range: %q", span)
+		}
+	}
+
+	return nil
+}
+
+func (f cronField) value(value string) (int, error) {
+	i := slices.Index(f.names, strings.ToLower(value))
+	if i != -1 {
+		return i + f.min, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("value out of range %d-%d: %q", f.min, f.max, value)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,196 @@
+package resources
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCronScheduleUnit(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"*/15 0-6,22-23 1 jan-mar mon-fri",
+		"0 12 */2 * 7",
+		"5-55/10 * * DEC sun",
+		"@reboot",
+	}
+
+	for _, schedule := range valid {
+		t.Run(schedule, func(t *testing.T) {
+			assert.NoError(t, validateCronSchedule(schedule))
+		})
+	}
+
+	invalid := []string{
+		"* * * *",
+		"*  * * * *",
+		"60 * * * *",
+		"* * 0 * *",
+		"* * * * 8",
+		"5/10 * * * *",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"* * * foo *",
+		"@sometimes",
+	}
+
+	for _, schedule := range invalid {
+		t.Run(schedule, func(t *testing.T) {
+			assert.Error(t, validateCronSchedule(schedule))
+		})
+	}
+}
+
+func TestNewCronJobUnit(t *testing.T) {
+	_, err := NewCronJob("backup", "* * * 13 *", "/usr/local/bin/backup")
+	assert.ErrorContains(t, err, "invalid cron schedule of backup: invalid month")
+
+	_, err = NewCronJob("backup.job", "@daily", "/usr/local/bin/backup")
+	assert.ErrorContains(t, err, "invalid cron job name")
+
+	_, err = NewCronJob("backup", "@daily", "/usr/local/bin/backup", WithCronFile("align.cron"))
+	assert.ErrorContains(t, err, "would be ignored by cron")
+
+	_, err = NewCronJob("backup", "@daily", "/usr/local/bin/backup", WithCronEnv("BAD-KEY", "value"))
+	assert.ErrorContains(t, err, "invalid cron environment variable")
+
+	_, err = NewCronJob("backup", "@daily", "/usr/local/bin/backup", WithCronUser("root /bin/evil\n"))
+	assert.ErrorContains(t, err, "invalid cron user")
+
+	job, err := NewCronJob(
+		"backup",
+		"0 3 * * *",
+		"date +%F >> /var/log/backup",
+		WithCronUser("backup"),
+		WithCronEnv("TARGET", "it's remote"),
+		WithCronEnv("LEVEL", "full"),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "cron:backup", job.Id())
+	assert.Equal(t, `0 3 * * * backup export LEVEL='full'; export TARGET='it'\''s remote'; date +\%F >> /var/log/backup`, job.entry())
+}
+
+func TestCronJobCheckIntegration(t *testing.T) {
+	t.Run("entry in cron file is installed and removed", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "align")
+
+		err := os.WriteFile(path, []byte("# other tool\n0 * * * * root /bin/other\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		job, err := NewCronJob("backup", "@daily", "/usr/local/bin/backup", WithCronDir(dir))
+		assert.NoError(t, err)
+
+		actual, err := job.Check()
+		assertCorrections(t, []Correction{job.install}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, job.install())
+
+		corrections, err := job.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		job, err = NewCronJob("backup", "@hourly", "/usr/local/bin/backup", WithCronDir(dir))
+		assert.NoError(t, err)
+
+		actual, err = job.Check()
+		assertCorrections(t, []Correction{job.install}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, job.install())
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "# other tool\n0 * * * * root /bin/other\n# align: backup\n@hourly root /usr/local/bin/backup\n", string(content))
+
+		job, err = NewCronJob("backup", "@hourly", "/usr/local/bin/backup", WithCronDir(dir), WithCronEnsure(EnsureAbsent))
+		assert.NoError(t, err)
+
+		actual, err = job.Check()
+		assertCorrections(t, []Correction{job.remove}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, job.remove())
+
+		content, err = os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "# other tool\n0 * * * * root /bin/other\n", string(content))
+	})
+
+	t.Run("lines after marker that are not entries are left alone", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "align")
+
+		err := os.WriteFile(path, []byte("# align: backup\nMAILTO=ops\n0 * * * * root /bin/other\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		job, err := NewCronJob("backup", "@daily", "/usr/local/bin/backup", WithCronDir(dir))
+		assert.NoError(t, err)
+
+		assert.NoError(t, job.install())
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "# align: backup\n@daily root /usr/local/bin/backup\nMAILTO=ops\n0 * * * * root /bin/other\n", string(content))
+
+		err = os.WriteFile(path, []byte("# align: backup\nMAILTO=ops\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.NoError(t, job.remove())
+
+		content, err = os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "MAILTO=ops\n", string(content))
+	})
+
+	t.Run("entry in user crontab is installed", func(t *testing.T) {
+		crontab := "MAILTO=ops\n"
+		runner := &fakeRunner{answer: func(command Command) CommandResult {
+			if command.String() == "crontab -l -u deploy" {
+				return CommandResult{Stdout: []byte(crontab)}
+			}
+
+			crontab = string(command.Stdin)
+			return CommandResult{}
+		}}
+
+		job, err := NewCronJob(
+			"cleanup", "*/5 * * * *", "/bin/cleanup",
+			WithCronUser("deploy"), WithUserCrontab(), WithCronRunner(runner),
+		)
+		assert.NoError(t, err)
+
+		actual, err := job.Check()
+		assertCorrections(t, []Correction{job.install}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, job.install())
+		assert.Equal(t, "MAILTO=ops\n# align: cleanup\n*/5 * * * * /bin/cleanup\n", crontab)
+
+		corrections, err := job.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("user without crontab", func(t *testing.T) {
+		runner := &fakeRunner{answer: func(command Command) CommandResult {
+			return CommandResult{Stderr: []byte("no crontab for deploy\n"), ExitCode: 1}
+		}}
+
+		job, err := NewCronJob("cleanup", "@daily", "/bin/cleanup", WithCronUser("deploy"), WithUserCrontab(), WithCronRunner(runner))
+		assert.NoError(t, err)
+
+		lines, err := job.readEntries()
+		assert.NoError(t, err)
+		assert.Empty(t, lines)
+	})
+}
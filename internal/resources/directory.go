@@ -0,0 +1,397 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/scherepiuk/align/internal/logger"
+	"github.com/scherepiuk/align/internal/types"
+	"github.com/scherepiuk/align/internal/utils"
+)
+
+const defaultDirectoryMode = os.FileMode(0o755)
+
+type Directory struct {
+	BaseDependant
+	path      string
+	mode      types.Optional[os.FileMode]
+	owner     types.Optional[string]
+	group     types.Optional[string]
+	recursive bool
+}
+
+func NewDirectory(path string, opts ...DirectoryOption) *Directory {
+	directory := &Directory{path: path}
+
+	for _, opt := range opts {
+		opt(directory)
+	}
+
+	return directory
+}
+
+type DirectoryOption func(directory *Directory)
+
+func WithDirectoryMode(mode os.FileMode) DirectoryOption {
+	return func(directory *Directory) {
+		logger.Global().Info("specifying directory mode", "path", directory.path, "mode", mode)
+		directory.mode = types.NewOptional(mode)
+	}
+}
+
+func WithDirectoryOwner(owner string) DirectoryOption {
+	return func(directory *Directory) {
+		logger.Global().Info("specifying directory owner", "path", directory.path, "owner", owner)
+		directory.owner = types.NewOptional(owner)
+	}
+}
+
+func WithDirectoryGroup(group string) DirectoryOption {
+	return func(directory *Directory) {
+		logger.Global().Info("specifying directory group", "path", directory.path, "group", group)
+		directory.group = types.NewOptional(group)
+	}
+}
+
+// WithRecursive applies the settings to the whole tree. Owner and group are
+// applied to every entry, while mode is applied to subdirectories only, since
+// a directory mode (e.g., 0755) rarely makes sense for regular files.
+func WithRecursive() DirectoryOption {
+	return func(directory *Directory) {
+		logger.Global().Info("specifying recursive directory", "path", directory.path)
+		directory.recursive = true
+	}
+}
+
+func (d *Directory) Id() string {
+	return d.path
+}
+
+func (d *Directory) Check() ([]Correction, error) {
+	stat, err := os.Stat(d.path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Global().Warn("directory does not exist", "path", d.path)
+		corrections := []Correction{
+			d.create,
+			d.changeMode,
+			d.changeOwner,
+			d.changeGroup,
+		}
+		return corrections, ErrUnalignedResource
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat directory: %w", err)
+	}
+
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("path is not a directory: %s", d.path)
+	}
+
+	uid, gid := -1, -1
+
+	if d.owner.Ok() {
+		uid, err = lookupUser(d.owner.Value())
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup user: %w", err)
+		}
+	}
+
+	if d.group.Ok() {
+		gid, err = lookupGroup(d.group.Value())
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup group: %w", err)
+		}
+	}
+
+	var wrongMode, wrongOwner, wrongGroup bool
+
+	err = d.walk(func(path string, stat os.FileInfo) error {
+		if d.mode.Ok() && stat.IsDir() && permissionBits(stat.Mode()) != d.mode.Value() {
+			logger.Global().Warn(
+				"directory has wrong mode", "path", path,
+				"mode.actual", utils.FormatFileMode(permissionBits(stat.Mode())),
+				"mode.target", utils.FormatFileMode(d.mode.Value()),
+			)
+			wrongMode = true
+		}
+
+		actualUid, actualGid := fileOwnership(stat)
+
+		if uid != -1 && actualUid != uid {
+			logger.Global().Warn(
+				"directory entry has wrong owner", "path", path,
+				"uid.actual", actualUid, "uid.target", uid,
+			)
+			wrongOwner = true
+		}
+
+		if gid != -1 && actualGid != gid {
+			logger.Global().Warn(
+				"directory entry has wrong group", "path", path,
+				"gid.actual", actualGid, "gid.target", gid,
+			)
+			wrongGroup = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	corrections := make([]Correction, 0)
+
+	if wrongMode {
+		corrections = append(corrections, d.changeMode)
+	}
+
+	if wrongOwner {
+		corrections = append(corrections, d.changeOwner)
+	}
+
+	if wrongGroup {
+		corrections = append(corrections, d.changeGroup)
+	}
+
+	if len(corrections) > 0 {
+		return corrections, ErrUnalignedResource
+	}
+
+	return nil, nil
+}
+
+func (d *Directory) Watch(
+	ctx context.Context,
+	correctionsCh chan<- []Correction,
+	errCh chan<- error,
+) {
+	err := check(d, correctionsCh)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		errCh <- err
+		return
+	}
+	defer watcher.Close()
+
+	err = d.addWatches(ctx, watcher)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			errCh <- ctx.Err()
+			return
+
+		case err := <-watcher.Errors:
+			errCh <- err
+			return
+
+		case event := <-watcher.Events:
+			logger.Global().Debug("got fsnotify event", "event", event.String())
+
+			// NOTE: Watching a directory reports events for its entries too,
+			// they are only relevant when the whole tree is managed.
+			if !d.recursive && event.Name != d.path {
+				continue
+			}
+
+			if d.recursive && event.Has(fsnotify.Create) {
+				err := watchTree(watcher, event.Name)
+				if err != nil {
+					errCh <- err
+					return
+				}
+			}
+
+			err := check(d, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+			// NOTE: The watch is dropped together with the directory, so it has
+			// to be added again once the directory is recreated.
+			if event.Name == d.path && event.Has(fsnotify.Remove|fsnotify.Rename) {
+				err = d.addWatches(ctx, watcher)
+				if err != nil {
+					errCh <- err
+					return
+				}
+			}
+		}
+	}
+}
+
+func (d *Directory) addWatches(ctx context.Context, watcher *fsnotify.Watcher) error {
+	err := utils.Retry(
+		ctx,
+		func() error { return watcher.Add(d.path) },
+		100*time.Millisecond,
+		os.ErrNotExist,
+	)
+	if err != nil {
+		return err
+	}
+
+	if !d.recursive {
+		return nil
+	}
+
+	return d.walk(func(path string, stat os.FileInfo) error {
+		if path == d.path || !stat.IsDir() {
+			return nil
+		}
+
+		return watcher.Add(path)
+	})
+}
+
+// watchTree adds the watches of a directory created in the tree, and of the
+// directories created in it before its watch was added. The ones removed in
+// the meantime are skipped.
+func watchTree(watcher *fsnotify.Watcher, root string) error {
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		err = watcher.Add(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to watch directory: %w", err)
+	}
+
+	return nil
+}
+
+// walk calls the callback for the directory itself and, in recursive mode,
+// for every entry of the tree. Symbolic links are not followed, and the
+// entries removed during the walk are skipped.
+func (d *Directory) walk(callback func(path string, stat os.FileInfo) error) error {
+	if !d.recursive {
+		stat, err := os.Stat(d.path)
+		if err != nil {
+			return err
+		}
+
+		return callback(d.path, stat)
+	}
+
+	return filepath.WalkDir(d.path, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil {
+			var stat os.FileInfo
+			stat, err = entry.Info()
+			if err == nil {
+				err = callback(path, stat)
+			}
+		}
+
+		if path != d.path && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	})
+}
+
+func (d *Directory) create() error {
+	mode := defaultDirectoryMode
+	if d.mode.Ok() {
+		mode = d.mode.Value()
+	}
+
+	err := os.MkdirAll(d.path, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Directory) changeMode() error {
+	if !d.mode.Ok() {
+		return nil
+	}
+
+	err := d.walk(func(path string, stat os.FileInfo) error {
+		if !stat.IsDir() {
+			return nil
+		}
+
+		return os.Chmod(path, d.mode.Value())
+	})
+	if err != nil {
+		return fmt.Errorf("failed to change directory's mode: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Directory) changeOwner() error {
+	if !d.owner.Ok() {
+		return nil
+	}
+
+	uid, err := lookupUser(d.owner.Value())
+	if err != nil {
+		return fmt.Errorf("failed to lookup user: %w", err)
+	}
+
+	err = d.walk(func(path string, _ os.FileInfo) error {
+		return os.Lchown(path, uid, -1)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to change directory's owner: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Directory) changeGroup() error {
+	if !d.group.Ok() {
+		return nil
+	}
+
+	gid, err := lookupGroup(d.group.Value())
+	if err != nil {
+		return fmt.Errorf("failed to lookup group: %w", err)
+	}
+
+	err = d.walk(func(path string, _ os.FileInfo) error {
+		return os.Lchown(path, -1, gid)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to change directory's group: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package resources
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/scherepiuk/align/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDirectoryUnit(t *testing.T) {
+	t.Run("new directory without options", func(t *testing.T) {
+		directory := NewDirectory("/tmp/testing")
+
+		assert.Equal(t, "/tmp/testing", directory.path)
+		assert.Equal(t, "/tmp/testing", directory.Id())
+		assert.Equal(t, types.Optional[os.FileMode]{}, directory.mode)
+		assert.Equal(t, types.Optional[string]{}, directory.owner)
+		assert.Equal(t, types.Optional[string]{}, directory.group)
+		assert.False(t, directory.recursive)
+	})
+
+	t.Run("new directory with multiple options", func(t *testing.T) {
+		directory := NewDirectory(
+			"/tmp/testing",
+			WithDirectoryMode(0o750),
+			WithDirectoryOwner("owner"),
+			WithDirectoryGroup("group"),
+			WithRecursive(),
+		)
+
+		assert.Equal(t, "/tmp/testing", directory.path)
+		assert.Equal(t, "/tmp/testing", directory.Id())
+		assert.Equal(t, types.NewOptional[os.FileMode](0o750), directory.mode)
+		assert.Equal(t, types.NewOptional("owner"), directory.owner)
+		assert.Equal(t, types.NewOptional("group"), directory.group)
+		assert.True(t, directory.recursive)
+	})
+}
+
+func TestDirectoryCheckIntegration(t *testing.T) {
+	t.Run("directory does not exist", func(t *testing.T) {
+		path := testFilePath()
+
+		directory := NewDirectory(path)
+		expected := []Correction{
+			directory.create,
+			directory.changeMode,
+			directory.changeOwner,
+			directory.changeGroup,
+		}
+
+		actual, err := directory.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+	})
+
+	t.Run("path is not a directory", func(t *testing.T) {
+		path := testFilePath()
+
+		err := os.WriteFile(path, nil, 0o664)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(path) })
+
+		directory := NewDirectory(path)
+
+		corrections, err := directory.Check()
+		assert.Nil(t, corrections)
+		assert.ErrorContains(t, err, "path is not a directory")
+	})
+
+	t.Run("directory has wrong mode", func(t *testing.T) {
+		path := testDirectory(t)
+
+		directory := NewDirectory(path, WithDirectoryMode(0o700))
+		expected := []Correction{directory.changeMode}
+
+		actual, err := directory.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+	})
+
+	t.Run("subdirectory has wrong mode", func(t *testing.T) {
+		path := testDirectory(t)
+
+		subdirectory := filepath.Join(path, "subdirectory")
+		err := os.Mkdir(subdirectory, 0o700)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.WriteFile(filepath.Join(subdirectory, "file"), nil, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		directory := NewDirectory(path, WithDirectoryMode(0o755))
+
+		corrections, err := directory.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		directory = NewDirectory(path, WithDirectoryMode(0o755), WithRecursive())
+		expected := []Correction{directory.changeMode}
+
+		actual, err := directory.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		err = directory.changeMode()
+		assert.NoError(t, err)
+
+		corrections, err = directory.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("entries removed during walk are skipped", func(t *testing.T) {
+		path := testDirectory(t)
+
+		for _, name := range []string{"a", "b"} {
+			err := os.WriteFile(filepath.Join(path, name), nil, 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		directory := NewDirectory(path, WithRecursive())
+
+		walked := make([]string, 0)
+		err := directory.walk(func(entry string, _ os.FileInfo) error {
+			walked = append(walked, filepath.Base(entry))
+			return os.Remove(filepath.Join(path, "b"))
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{filepath.Base(path), "a"}, walked)
+	})
+
+	t.Run("directory is aligned", func(t *testing.T) {
+		path := testDirectory(t)
+
+		directory := NewDirectory(path, WithDirectoryMode(0o755))
+
+		corrections, err := directory.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+}
+
+func TestDirectoryWatchIntegration(t *testing.T) {
+	t.Run("directory's mode has been changed", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		t.Cleanup(cancel)
+
+		path := testDirectory(t)
+		correctionsCh, errCh := make(chan []Correction), make(chan error)
+
+		directory := NewDirectory(path, WithDirectoryMode(0o755))
+		expected := []Correction{directory.changeMode}
+
+		go directory.Watch(ctx, correctionsCh, errCh)
+		time.Sleep(time.Second)
+
+		err := os.Chmod(path, 0o700)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual := <-correctionsCh
+		assertCorrections(t, expected, actual)
+	})
+}
+
+func testDirectory(t *testing.T) string {
+	path := testFilePath()
+
+	err := os.Mkdir(path, 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(path) })
+
+	err = os.Chmod(path, 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
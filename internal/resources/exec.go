@@ -0,0 +1,271 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/scherepiuk/align/internal/logger"
+	"github.com/scherepiuk/align/internal/types"
+)
+
+const (
+	defaultExecTimeout = 5 * time.Minute
+	defaultExecPolling = 30 * time.Second
+)
+
+// Exec runs a shell command for the steps no other resource covers. Whether
+// the command has to run is decided by its guards: the file it creates (see
+// WithCreates), and the commands telling it's done (see WithUnless) or needed
+// (see WithOnlyIf). Without guards, it runs once, when align starts. A guard
+// or a command that fails (or times out) is only logged, so one broken step
+// doesn't stop the enforcement of the rest; a failed guard is retried with
+// the next poll.
+type Exec struct {
+	BaseDependant
+	name    string
+	command string
+	dir     string
+	env     []string
+	user    string
+	timeout time.Duration
+
+	creates types.Optional[string]
+	unless  types.Optional[string]
+	onlyIf  types.Optional[string]
+
+	runner       CommandRunner
+	pollInterval time.Duration
+}
+
+func NewExec(name, command string, opts ...ExecOption) *Exec {
+	exec := &Exec{
+		name:         name,
+		command:      command,
+		timeout:      defaultExecTimeout,
+		runner:       NewExecRunner(),
+		pollInterval: defaultExecPolling,
+	}
+
+	for _, opt := range opts {
+		opt(exec)
+	}
+
+	return exec
+}
+
+type ExecOption func(exec *Exec)
+
+// WithExecDir changes the working directory of the command and its guards.
+func WithExecDir(dir string) ExecOption {
+	return func(exec *Exec) {
+		logger.Global().Info("specifying exec directory", "name", exec.name, "dir", dir)
+		exec.dir = dir
+	}
+}
+
+func WithExecEnv(key, value string) ExecOption {
+	return func(exec *Exec) {
+		logger.Global().Info("specifying exec environment variable", "name", exec.name, "key", key)
+		exec.env = append(exec.env, key+"="+value)
+	}
+}
+
+// WithExecUser runs the command and its guards as the user.
+func WithExecUser(user string) ExecOption {
+	return func(exec *Exec) {
+		logger.Global().Info("specifying exec user", "name", exec.name, "user", user)
+		exec.user = user
+	}
+}
+
+// WithExecTimeout changes how long the command and each of its guards can
+// run, which is 5 minutes by default.
+func WithExecTimeout(timeout time.Duration) ExecOption {
+	return func(exec *Exec) {
+		logger.Global().Info("specifying exec timeout", "name", exec.name, "timeout", timeout)
+		exec.timeout = timeout
+	}
+}
+
+// WithCreates skips the command if the path (relative to the working
+// directory) exists.
+func WithCreates(path string) ExecOption {
+	return func(exec *Exec) {
+		logger.Global().Info("specifying exec creates guard", "name", exec.name, "path", path)
+		exec.creates = types.NewOptional(path)
+	}
+}
+
+// WithUnless skips the command if the guard command succeeds.
+func WithUnless(command string) ExecOption {
+	return func(exec *Exec) {
+		logger.Global().Info("specifying exec unless guard", "name", exec.name, "command", command)
+		exec.unless = types.NewOptional(command)
+	}
+}
+
+// WithOnlyIf skips the command unless the guard command succeeds.
+func WithOnlyIf(command string) ExecOption {
+	return func(exec *Exec) {
+		logger.Global().Info("specifying exec onlyif guard", "name", exec.name, "command", command)
+		exec.onlyIf = types.NewOptional(command)
+	}
+}
+
+// WithExecRunner changes how the commands are run, they are executed on the
+// host by default.
+func WithExecRunner(runner CommandRunner) ExecOption {
+	return func(exec *Exec) {
+		logger.Global().Info("specifying exec runner", "name", exec.name, "runner", fmt.Sprintf("%T", runner))
+		exec.runner = runner
+	}
+}
+
+// WithExecPolling changes how often the guards are re-checked.
+func WithExecPolling(interval time.Duration) ExecOption {
+	return func(exec *Exec) {
+		logger.Global().Info("specifying exec polling", "name", exec.name, "interval", interval)
+		exec.pollInterval = interval
+	}
+}
+
+func (e *Exec) Id() string {
+	return "exec:" + e.name
+}
+
+func (e *Exec) Check() ([]Correction, error) {
+	if e.creates.Ok() {
+		_, err := os.Stat(e.createsPath())
+		if err == nil {
+			return nil, nil
+		}
+
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to stat created path: %w", err)
+		}
+	}
+
+	if e.unless.Ok() {
+		result, err := e.run(e.unless.Value())
+		if err != nil {
+			logger.Global().Error("failed to run unless guard", "name", e.name, "error", err)
+			return nil, nil
+		}
+
+		if result.ExitCode == 0 {
+			return nil, nil
+		}
+	}
+
+	if e.onlyIf.Ok() {
+		result, err := e.run(e.onlyIf.Value())
+		if err != nil {
+			logger.Global().Error("failed to run onlyif guard", "name", e.name, "error", err)
+			return nil, nil
+		}
+
+		if result.ExitCode != 0 {
+			return nil, nil
+		}
+	}
+
+	logger.Global().Warn("command has to run", "name", e.name)
+	return []Correction{e.execute}, ErrUnalignedResource
+}
+
+// Watch polls the guards (see WithExecPolling). A command without guards has
+// nothing to poll, so it is only run by the initial check of the watcher,
+// which runs it in the order of the dependencies.
+func (e *Exec) Watch(
+	ctx context.Context,
+	correctionsCh chan<- []Correction,
+	errCh chan<- error,
+) {
+	if !e.guarded() {
+		<-ctx.Done()
+		errCh <- ctx.Err()
+		return
+	}
+
+	err := check(e, correctionsCh)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	ticker := time.NewTicker(e.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			errCh <- ctx.Err()
+			return
+
+		case <-ticker.C:
+			err := check(e, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}
+}
+
+func (e *Exec) guarded() bool {
+	return e.creates.Ok() || e.unless.Ok() || e.onlyIf.Ok()
+}
+
+func (e *Exec) createsPath() string {
+	path := e.creates.Value()
+	if !filepath.IsAbs(path) && e.dir != "" {
+		path = filepath.Join(e.dir, path)
+	}
+
+	return path
+}
+
+func (e *Exec) execute() error {
+	result, err := e.run(e.command)
+	if err != nil {
+		logger.Global().Error("failed to run command", "name", e.name, "error", err)
+		return nil
+	}
+
+	logger.Global().Info(
+		"command finished", "name", e.name, "exitCode", result.ExitCode,
+		"stdout", string(result.Stdout), "stderr", string(result.Stderr),
+	)
+
+	if result.ExitCode != 0 {
+		logger.Global().Error("command failed", "name", e.name, "exitCode", result.ExitCode, "output", result.output())
+		return nil
+	}
+
+	if e.creates.Ok() {
+		_, err := os.Stat(e.createsPath())
+		if errors.Is(err, os.ErrNotExist) {
+			logger.Global().Warn("command did not create its path", "name", e.name, "path", e.createsPath())
+		}
+	}
+
+	return nil
+}
+
+// run runs the command with sh, under the timeout.
+func (e *Exec) run(command string) (CommandResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	return e.runner.Run(ctx, Command{
+		Name: "sh",
+		Args: []string{"-c", command},
+		Dir:  e.dir,
+		Env:  e.env,
+		User: e.user,
+	})
+}
@@ -0,0 +1,97 @@
+package resources
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecCheckIntegration(t *testing.T) {
+	t.Run("creates guard", func(t *testing.T) {
+		dir := t.TempDir()
+		exec := NewExec("generate", `echo "$KEY" > key`, WithExecDir(dir), WithExecEnv("KEY", "secret"), WithCreates("key"))
+
+		actual, err := exec.Check()
+		assertCorrections(t, []Correction{exec.execute}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, exec.execute())
+
+		content, err := os.ReadFile(filepath.Join(dir, "key"))
+		assert.NoError(t, err)
+		assert.Equal(t, "secret\n", string(content))
+
+		corrections, err := exec.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("unless and onlyif guards", func(t *testing.T) {
+		dir := t.TempDir()
+		marker := filepath.Join(dir, "migrated")
+
+		exec := NewExec("migrate", "touch "+marker, WithUnless("test -e "+marker), WithOnlyIf("true"))
+
+		actual, err := exec.Check()
+		assertCorrections(t, []Correction{exec.execute}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, exec.execute())
+
+		corrections, err := exec.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		exec = NewExec("migrate", "true", WithOnlyIf("false"))
+
+		corrections, err = exec.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("failed command", func(t *testing.T) {
+		exec := NewExec("fail", "echo broken >&2; exit 2")
+
+		err := exec.execute()
+		assert.NoError(t, err)
+	})
+
+	t.Run("timed out guard", func(t *testing.T) {
+		exec := NewExec("slow", "true", WithUnless("sleep 5"), WithExecTimeout(50*time.Millisecond))
+
+		corrections, err := exec.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("command without guards is not rerun by watch", func(t *testing.T) {
+		runner := &fakeRunner{}
+		exec := NewExec("once", "true", WithExecRunner(runner))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		correctionsCh := make(chan []Correction, 1)
+		errCh := make(chan error, 1)
+
+		go exec.Watch(ctx, correctionsCh, errCh)
+		cancel()
+
+		assert.ErrorIs(t, <-errCh, context.Canceled)
+		assert.Empty(t, correctionsCh)
+		assert.Empty(t, runner.commands)
+	})
+
+	t.Run("command runs as user", func(t *testing.T) {
+		runner := &fakeRunner{answer: func(command Command) CommandResult {
+			assert.Equal(t, "nobody", command.User)
+			return CommandResult{Stdout: []byte("nobody\n")}
+		}}
+		exec := NewExec("as-user", "whoami", WithExecUser("nobody"), WithExecRunner(runner))
+
+		assert.NoError(t, exec.execute())
+		assert.Equal(t, []string{"sh -c whoami"}, runner.commands)
+	})
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"slices"
-	"syscall"
+	"text/template"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -15,17 +17,21 @@ import (
 	"github.com/scherepiuk/align/internal/utils"
 )
 
-// TODO: sc: Allow specifying the content. Figure out a way to restore it
-// without incurring a huge performance penalty (e.g., storing it in RAM.)
 // TODO: sc: Watch function fires check twice: once when fsnotify emits an
 // event due to change, and for the second time when correction is applied.
 
 type File struct {
 	BaseDependant
-	path  string
-	mode  types.Optional[os.FileMode]
-	owner types.Optional[string]
-	group types.Optional[string]
+	path    string
+	mode    types.Optional[os.FileMode]
+	owner   types.Optional[string]
+	group   types.Optional[string]
+	content types.Optional[fileContent]
+	ensure  Ensure
+	backup  types.Optional[*Backup]
+	xattrs  types.Optional[map[string]string]
+	acl     types.Optional[[]string]
+	flags   types.Optional[[]string]
 }
 
 func NewFile(path string, opts ...FileOption) *File {
@@ -61,20 +67,117 @@ func WithGroup(group string) FileOption {
 	}
 }
 
+func WithContent(content string) FileOption {
+	return func(file *File) {
+		logger.Global().Info("specifying file content", "path", file.path, "size", len(content))
+		file.content = types.NewOptional(newStaticContent(content))
+	}
+}
+
+// WithContentFrom makes the file mirror the content of the source file. The
+// source is read on every check, so changes to it are picked up as well.
+func WithContentFrom(source string) FileOption {
+	return func(file *File) {
+		logger.Global().Info("specifying file content source", "path", file.path, "source", source)
+		file.content = types.NewOptional(newSourceContent(source))
+	}
+}
+
+// WithTemplate renders the file's content from the text/template, which has
+// access to the variables (as .Vars) and to the host facts (as .Facts).
+func WithTemplate(text string, vars map[string]any) FileOption {
+	return func(file *File) {
+		// NOTE: The variables are not logged, since they may hold secrets.
+		logger.Global().Info("specifying file template", "path", file.path)
+		parse := func() (*template.Template, error) {
+			return template.New(file.path).Parse(text)
+		}
+		file.content = types.NewOptional(newTemplateContent(parse, vars))
+	}
+}
+
+// WithTemplateFrom is like WithTemplate, but reads the template from the
+// source file. The file is watched, so changes to it are applied as well.
+func WithTemplateFrom(source string, vars map[string]any) FileOption {
+	return func(file *File) {
+		logger.Global().Info("specifying file template source", "path", file.path, "source", source)
+		parse := func() (*template.Template, error) {
+			return template.ParseFiles(source)
+		}
+		file.content = types.NewOptional(newTemplateContent(parse, vars, source))
+	}
+}
+
+func WithEnsure(ensure Ensure) FileOption {
+	return func(file *File) {
+		logger.Global().Info("specifying file ensure", "path", file.path, "ensure", ensure)
+		file.ensure = ensure
+	}
+}
+
+// WithXattr sets the extended attribute of the file. Attributes that are not
+// specified are left alone. May be used multiple times.
+func WithXattr(name, value string) FileOption {
+	return func(file *File) {
+		logger.Global().Info("specifying file xattr", "path", file.path, "xattr", name, "value", value)
+		xattrs := make(map[string]string)
+		if file.xattrs.Ok() {
+			xattrs = file.xattrs.Value()
+		}
+		xattrs[name] = value
+		file.xattrs = types.NewOptional(xattrs)
+	}
+}
+
+// WithACL sets the POSIX access ACL of the file (e.g., "user::rw-",
+// "user:alice:r--", "group::r--", "other::---"). Since the ACL defines the
+// mode bits too, it should agree with WithMode, if both are specified.
+func WithACL(entries ...string) FileOption {
+	return func(file *File) {
+		logger.Global().Info("specifying file acl", "path", file.path, "acl", entries)
+		file.acl = types.NewOptional(entries)
+	}
+}
+
+// WithFlags sets (e.g., "+i") or clears (e.g., "-a") the inode flags of the
+// file, as chattr does. Flags that are not specified are left alone.
+func WithFlags(flags ...string) FileOption {
+	return func(file *File) {
+		logger.Global().Info("specifying file flags", "path", file.path, "flags", flags)
+		file.flags = types.NewOptional(flags)
+	}
+}
+
+// WithBackup saves the file before its content is restored or it is removed.
+func WithBackup(backup *Backup) FileOption {
+	return func(file *File) {
+		logger.Global().Info("specifying file backup", "path", file.path, "dir", backup.dir)
+		file.backup = types.NewOptional(backup)
+	}
+}
+
 func (f *File) Id() string {
 	return f.path
 }
 
 func (f *File) Check() ([]Correction, error) {
+	if f.ensure == EnsureAbsent {
+		return f.checkAbsent()
+	}
+
 	stat, err := os.Stat(f.path)
 
 	if errors.Is(err, os.ErrNotExist) {
 		logger.Global().Warn("file does not exist", "path", f.path)
 		corrections := []Correction{
 			f.create,
+			f.changeContent,
 			f.changeMode,
 			f.changeOwner,
 			f.changeGroup,
+			f.changeXattrs,
+			f.changeACL,
+			f.changeFlags,
 		}
 		return corrections, ErrUnalignedResource
 	}
@@ -85,6 +188,26 @@ func (f *File) Check() ([]Correction, error) {
 
 	corrections := make([]Correction, 0)
 
+	if f.content.Ok() {
+		actual, err := utils.ChecksumFile(f.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute file's checksum: %w", err)
+		}
+
+		target, err := f.content.Value().checksum()
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute content's checksum: %w", err)
+		}
+
+		if actual != target {
+			logger.Global().Warn(
+				"file has wrong content", "path", f.path,
+				"checksum.actual", actual, "checksum.target", target,
+			)
+			corrections = append(corrections, f.changeContent)
+		}
+	}
+
 	if f.mode.Ok() && stat.Mode() != f.mode.Value() {
 		logger.Global().Warn(
 			"file has wrong mode", "path", f.path,
@@ -94,13 +217,9 @@ func (f *File) Check() ([]Correction, error) {
 		corrections = append(corrections, f.changeMode)
 	}
 
-	// TODO: sc: Getting linux-specific file info. All resources should be cross-platform.
-	linuxFileInfo, ok := stat.Sys().(*syscall.Stat_t)
-	if !ok {
-		panic("failed to get system-specific file info: not running on linux")
-	}
+	uid, gid := fileOwnership(stat)
 
-	owner, _ := lookupUid(int(linuxFileInfo.Uid))
+	owner, _ := lookupUid(uid)
 	if f.owner.Ok() && owner != f.owner.Value() {
 		logger.Global().Warn(
 			"file has wrong owner", "path", f.path,
@@ -109,7 +228,7 @@ func (f *File) Check() ([]Correction, error) {
 		corrections = append(corrections, f.changeOwner)
 	}
 
-	group, _ := lookupGid(int(linuxFileInfo.Gid))
+	group, _ := lookupGid(gid)
 	if f.group.Ok() && group != f.group.Value() {
 		logger.Global().Warn(
 			"file has wrong group", "path", f.path,
@@ -118,6 +237,80 @@ func (f *File) Check() ([]Correction, error) {
 		corrections = append(corrections, f.changeGroup)
 	}
 
+	if f.xattrs.Ok() {
+		wrongXattrs := false
+
+		for name, target := range f.xattrs.Value() {
+			actual, ok, err := getXattr(f.path, name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get file's xattr: %w", err)
+			}
+
+			if !ok || string(actual) != target {
+				logger.Global().Warn(
+					"file has wrong xattr", "path", f.path, "xattr", name,
+					"value.actual", string(actual), "value.target", target,
+				)
+				wrongXattrs = true
+			}
+		}
+
+		if wrongXattrs {
+			corrections = append(corrections, f.changeXattrs)
+		}
+	}
+
+	if f.acl.Ok() {
+		target, err := parseACL(f.acl.Value())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse acl: %w", err)
+		}
+
+		actual, err := f.readACL(stat.Mode())
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Equal(actual, target) {
+			logger.Global().Warn(
+				"file has wrong acl", "path", f.path,
+				"acl.actual", formatACL(actual), "acl.target", formatACL(target),
+			)
+			corrections = append(corrections, f.changeACL)
+		}
+	}
+
+	if f.flags.Ok() {
+		set, clear, err := parseFlags(f.flags.Value())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse flags: %w", err)
+		}
+
+		actual, err := getFlags(f.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get file's flags: %w", err)
+		}
+
+		wrongFlags := actual&set != set || actual&clear != 0
+		if wrongFlags {
+			logger.Global().Warn(
+				"file has wrong flags", "path", f.path,
+				"flags.actual", formatFlags(actual), "flags.target", f.flags.Value(),
+			)
+		}
+
+		// NOTE: Immutable and append-only files can't be corrected, so these
+		// flags are released first and set back once everything else is done.
+		if len(corrections) > 0 && actual&protectiveFlags != 0 {
+			corrections = append([]Correction{f.releaseFlags}, corrections...)
+			wrongFlags = true
+		}
+
+		if wrongFlags {
+			corrections = append(corrections, f.changeFlags)
+		}
+	}
+
 	if len(corrections) > 0 {
 		return corrections, ErrUnalignedResource
 	}
@@ -125,12 +318,78 @@ func (f *File) Check() ([]Correction, error) {
 	return nil, nil
 }
 
+// readACL returns the access ACL of the file. A file without an extended ACL
+// has the minimal one, which is derived from its mode.
+func (f *File) readACL(mode os.FileMode) ([]aclEntry, error) {
+	data, ok, err := getXattr(f.path, aclXattr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file's acl: %w", err)
+	}
+
+	if !ok {
+		return aclFromMode(mode), nil
+	}
+
+	entries, err := decodeACL(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode file's acl: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (f *File) checkAbsent() ([]Correction, error) {
+	_, err := os.Lstat(f.path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	logger.Global().Warn("file exists but should be absent", "path", f.path)
+	return []Correction{f.remove}, ErrUnalignedResource
+}
+
 func (f *File) Watch(
 	ctx context.Context,
 	correctionsCh chan<- []Correction,
 	errCh chan<- error,
 ) {
-	corrections, err := f.Check()
+	watch := fileWatch{path: f.path, absent: f.ensure == EnsureAbsent}
+	if f.content.Ok() {
+		watch.sources = f.content.Value().sources
+	}
+
+	// NOTE: Unlike xattrs and ACLs, changes of inode flags are not reported
+	// by inotify, so they have to be polled for.
+	if f.flags.Ok() {
+		watch.pollInterval = 5 * time.Second
+	}
+
+	watchFile(ctx, f, watch, correctionsCh, errCh)
+}
+
+// fileWatch describes what has to be watched for the checker backed by a file.
+type fileWatch struct {
+	path         string
+	absent       bool          // the file should not exist
+	sources      []string      // files the desired state is produced from
+	pollInterval time.Duration // zero disables polling
+}
+
+// watchFile re-runs the checker whenever the watched file changes. It is
+// shared by all of the resources that manage (a part of) a single file.
+func watchFile(
+	ctx context.Context,
+	checker Checker,
+	watch fileWatch,
+	correctionsCh chan<- []Correction,
+	errCh chan<- error,
+) {
+	corrections, err := checker.Check()
 	if errors.Is(err, ErrUnalignedResource) {
 		correctionsCh <- corrections
 	} else if err != nil {
@@ -145,9 +404,16 @@ func (f *File) Watch(
 	}
 	defer watcher.Close()
 
+	// NOTE: A file that should be absent can't be watched directly, so its
+	// parent directory is watched for the file to (re)appear instead.
+	watchedPath := watch.path
+	if watch.absent {
+		watchedPath = filepath.Dir(watch.path)
+	}
+
 	err = utils.Retry(
 		ctx,
-		func() error { return watcher.Add(f.path) },
+		func() error { return watcher.Add(watchedPath) },
 		100*time.Millisecond,
 		os.ErrNotExist,
 	)
@@ -156,6 +422,19 @@ func (f *File) Watch(
 		return
 	}
 
+	// NOTE: Desired state is re-evaluated whenever the files it's produced from change.
+	if !watch.absent {
+		for _, source := range watch.sources {
+			err = watcher.Add(source)
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}
+
+	// NOTE: Chmod is reported for most changes of attributes, i.e., mode,
+	// ownership, xattrs and ACLs.
 	targetOps := []fsnotify.Op{
 		fsnotify.Write,
 		fsnotify.Remove,
@@ -163,17 +442,39 @@ func (f *File) Watch(
 		fsnotify.Chmod,
 	}
 
+	if watch.absent {
+		targetOps = []fsnotify.Op{fsnotify.Create}
+	}
+
+	var pollCh <-chan time.Time
+	if watch.pollInterval > 0 {
+		ticker := time.NewTicker(watch.pollInterval)
+		defer ticker.Stop()
+		pollCh = ticker.C
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			errCh <- ctx.Err()
 			return
 
+		case <-pollCh:
+			err := check(checker, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
+			}
+
 		case event := <-watcher.Events:
+			if watch.absent && event.Name != filepath.Clean(watch.path) {
+				continue
+			}
+
 			logger.Global().Debug("got fsnotify event", "event", event.String())
 
 			if slices.Contains(targetOps, event.Op) {
-				corrections, err := f.Check()
+				corrections, err := checker.Check()
 				if errors.Is(err, ErrUnalignedResource) {
 					correctionsCh <- corrections
 				} else if err != nil {
@@ -199,6 +500,70 @@ func (f *File) create() error {
 	return nil
 }
 
+func (f *File) remove() error {
+	err := f.saveBackup()
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(f.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+
+	return nil
+}
+
+func (f *File) changeContent() error {
+	if !f.content.Ok() {
+		return nil
+	}
+
+	err := f.saveBackup()
+	if err != nil {
+		return err
+	}
+
+	src, err := f.content.Value().open()
+	if err != nil {
+		return fmt.Errorf("failed to open content: %w", err)
+	}
+	defer src.Close()
+
+	// NOTE: Writing in place (instead of renaming a temporary file over the
+	// original) keeps the inode, so the active fsnotify watch stays valid.
+	dst, err := os.OpenFile(f.path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to write file's content: %w", err)
+	}
+
+	err = dst.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+
+	return nil
+}
+
+func (f *File) saveBackup() error {
+	if !f.backup.Ok() {
+		return nil
+	}
+
+	err := f.backup.Value().Save(f.Id(), f.path)
+	if err != nil {
+		return fmt.Errorf("failed to back up file: %w", err)
+	}
+
+	return nil
+}
+
 func (f *File) changeMode() error {
 	if !f.mode.Ok() {
 		return nil
@@ -247,3 +612,73 @@ func (f *File) changeGroup() error {
 
 	return nil
 }
+
+func (f *File) changeXattrs() error {
+	if !f.xattrs.Ok() {
+		return nil
+	}
+
+	for name, value := range f.xattrs.Value() {
+		err := setXattr(f.path, name, []byte(value))
+		if err != nil {
+			return fmt.Errorf("failed to change file's xattr: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (f *File) changeACL() error {
+	if !f.acl.Ok() {
+		return nil
+	}
+
+	entries, err := parseACL(f.acl.Value())
+	if err != nil {
+		return fmt.Errorf("failed to parse acl: %w", err)
+	}
+
+	err = setXattr(f.path, aclXattr, encodeACL(entries))
+	if err != nil {
+		return fmt.Errorf("failed to change file's acl: %w", err)
+	}
+
+	return nil
+}
+
+func (f *File) changeFlags() error {
+	if !f.flags.Ok() {
+		return nil
+	}
+
+	set, clear, err := parseFlags(f.flags.Value())
+	if err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	flags, err := getFlags(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to get file's flags: %w", err)
+	}
+
+	err = setFlags(f.path, (flags|set)&^clear)
+	if err != nil {
+		return fmt.Errorf("failed to change file's flags: %w", err)
+	}
+
+	return nil
+}
+
+func (f *File) releaseFlags() error {
+	flags, err := getFlags(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to get file's flags: %w", err)
+	}
+
+	err = setFlags(f.path, flags&^protectiveFlags)
+	if err != nil {
+		return fmt.Errorf("failed to release file's flags: %w", err)
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package resources
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
@@ -54,6 +55,29 @@ func TestNewFileUnit(t *testing.T) {
 		assert.Equal(t, types.NewOptional("group"), file.group)
 	})
 
+	t.Run("new file with content option", func(t *testing.T) {
+		file := NewFile("/tmp/testing", WithContent("content"))
+
+		assert.Equal(t, "/tmp/testing", file.path)
+		assert.Equal(t, "/tmp/testing", file.Id())
+		assert.Equal(t, types.Optional[os.FileMode]{}, file.mode)
+		assert.Equal(t, types.Optional[string]{}, file.owner)
+		assert.Equal(t, types.Optional[string]{}, file.group)
+		assert.True(t, file.content.Ok())
+
+		checksum, err := file.content.Value().checksum()
+		assert.NoError(t, err)
+		assert.Equal(t, "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73", checksum)
+	})
+
+	t.Run("new file with ensure option", func(t *testing.T) {
+		file := NewFile("/tmp/testing", WithEnsure(EnsureAbsent))
+
+		assert.Equal(t, "/tmp/testing", file.path)
+		assert.Equal(t, "/tmp/testing", file.Id())
+		assert.Equal(t, EnsureAbsent, file.ensure)
+	})
+
 	t.Run("new file with multiple options", func(t *testing.T) {
 		file := NewFile(
 			"/tmp/testing",
@@ -93,9 +117,13 @@ func TestFileCheckIntegration(t *testing.T) {
 		file := NewFile(path)
 		expected := []Correction{
 			file.create,
+			file.changeContent,
 			file.changeMode,
 			file.changeOwner,
 			file.changeGroup,
+			file.changeXattrs,
+			file.changeACL,
+			file.changeFlags,
 		}
 
 		actual, err := file.Check()
@@ -154,6 +182,166 @@ func TestFileCheckIntegration(t *testing.T) {
 		assert.ErrorIs(t, err, ErrUnalignedResource)
 	})
 
+	t.Run("file has wrong content", func(t *testing.T) {
+		path := testFilePath()
+
+		err := os.WriteFile(path, []byte("actual"), 0o664)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(path) })
+
+		file := NewFile(path, WithContent("target"))
+		expected := []Correction{file.changeContent}
+
+		actual, err := file.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+	})
+
+	t.Run("file has wrong content from source", func(t *testing.T) {
+		path, source := testFilePath(), testFilePath()
+
+		err := os.WriteFile(path, []byte("actual"), 0o664)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(path) })
+
+		err = os.WriteFile(source, []byte("target"), 0o664)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(source) })
+
+		file := NewFile(path, WithContentFrom(source))
+		expected := []Correction{file.changeContent}
+
+		actual, err := file.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		err = file.changeContent()
+		assert.NoError(t, err)
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "target", string(content))
+	})
+
+	t.Run("file has wrong templated content", func(t *testing.T) {
+		path := testFilePath()
+
+		err := os.WriteFile(path, []byte("actual"), 0o664)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(path) })
+
+		file := NewFile(
+			path,
+			WithTemplate("{{ .Facts.Hostname }}:{{ .Vars.port }}", map[string]any{"port": 8080}),
+		)
+		expected := []Correction{file.changeContent}
+
+		actual, err := file.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		err = file.changeContent()
+		assert.NoError(t, err)
+
+		hostname, err := os.Hostname()
+		assert.NoError(t, err)
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%s:8080", hostname), string(content))
+
+		corrections, err := file.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("file has invalid template", func(t *testing.T) {
+		path := testFilePath()
+
+		err := os.WriteFile(path, nil, 0o664)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(path) })
+
+		file := NewFile(path, WithTemplate("{{ .Vars.port ", nil))
+
+		corrections, err := file.Check()
+		assert.Nil(t, corrections)
+		assert.ErrorContains(t, err, "failed to parse template")
+	})
+
+	t.Run("file is left alone when template fails midway", func(t *testing.T) {
+		path := testFilePath()
+
+		err := os.WriteFile(path, []byte("actual"), 0o664)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(path) })
+
+		fail := func() (string, error) { return "", errors.New("boom") }
+		file := NewFile(path, WithTemplate("before {{ call .Vars.fail }} after", map[string]any{"fail": fail}))
+
+		err = file.changeContent()
+		assert.ErrorContains(t, err, "boom")
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "actual", string(content))
+	})
+
+	t.Run("file content is aligned", func(t *testing.T) {
+		path := testFilePath()
+
+		err := os.WriteFile(path, []byte("target"), 0o664)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(path) })
+
+		file := NewFile(path, WithContent("target"))
+
+		corrections, err := file.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("file exists but should be absent", func(t *testing.T) {
+		path := testFilePath()
+
+		err := os.WriteFile(path, nil, 0o664)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(path) })
+
+		file := NewFile(path, WithEnsure(EnsureAbsent))
+		expected := []Correction{file.remove}
+
+		actual, err := file.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+	})
+
+	t.Run("file is absent", func(t *testing.T) {
+		path := testFilePath()
+
+		file := NewFile(path, WithEnsure(EnsureAbsent))
+
+		corrections, err := file.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
 	t.Run("file is aligned", func(t *testing.T) {
 		path := testFilePath()
 
@@ -210,9 +398,13 @@ func TestFileWatchIntegration(t *testing.T) {
 		file := NewFile(path)
 		expected := []Correction{
 			file.create,
+			file.changeContent,
 			file.changeMode,
 			file.changeOwner,
 			file.changeGroup,
+			file.changeXattrs,
+			file.changeACL,
+			file.changeFlags,
 		}
 
 		go file.Watch(ctx, correctionsCh, errCh)
@@ -227,6 +419,29 @@ func TestFileWatchIntegration(t *testing.T) {
 		assertCorrections(t, expected, actual)
 	})
 
+	t.Run("file has been created but should be absent", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		t.Cleanup(cancel)
+
+		path := testFilePath()
+		correctionsCh, errCh := make(chan []Correction), make(chan error)
+
+		file := NewFile(path, WithEnsure(EnsureAbsent))
+		expected := []Correction{file.remove}
+
+		go file.Watch(ctx, correctionsCh, errCh)
+		time.Sleep(time.Second)
+
+		err := os.WriteFile(path, nil, 0o664)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(path) })
+
+		actual := <-correctionsCh
+		assertCorrections(t, expected, actual)
+	})
+
 	t.Run("file's mode has been changed", func(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		t.Cleanup(cancel)
@@ -0,0 +1,254 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/user"
+	"slices"
+
+	"github.com/scherepiuk/align/internal/logger"
+	"github.com/scherepiuk/align/internal/types"
+)
+
+type Group struct {
+	BaseDependant
+	name    string
+	gid     types.Optional[int]
+	gids    types.Optional[idRange]
+	system  bool
+	members types.Optional[[]string]
+
+	backend AccountBackend
+}
+
+// NewGroup manages the group by its name. The gid is allocated when the group
+// is created, unless it is given (see WithGroupGid).
+func NewGroup(name string, opts ...GroupOption) *Group {
+	group := &Group{name: name, backend: NewShadowUtilsBackend()}
+
+	for _, opt := range opts {
+		opt(group)
+	}
+
+	return group
+}
+
+type GroupOption func(group *Group)
+
+func WithGroupGid(gid int) GroupOption {
+	return func(group *Group) {
+		logger.Global().Info("specifying group gid", "name", group.name, "gid", gid)
+		group.gid = types.NewOptional(gid)
+	}
+}
+
+// WithGidRange changes the range the gid is allocated from, which is the
+// regular (or the system, see WithSystemGroup) range by default.
+func WithGidRange(min, max int) GroupOption {
+	return func(group *Group) {
+		logger.Global().Info("specifying group gid range", "name", group.name, "min", min, "max", max)
+		group.gids = types.NewOptional(idRange{min: min, max: max})
+	}
+}
+
+// WithSystemGroup creates the group as a system one. It only affects the
+// creation, an existing group is never converted.
+func WithSystemGroup() GroupOption {
+	return func(group *Group) {
+		logger.Global().Info("specifying system group", "name", group.name)
+		group.system = true
+	}
+}
+
+// WithMembers makes the listed users the only members of the group. The Users
+// in the exact mode (see WithMembership) leave the group alone while they are
+// listed, once they are linked (see LinkAccounts).
+func WithMembers(members ...string) GroupOption {
+	return func(group *Group) {
+		logger.Global().Info("specifying group members", "name", group.name, "members", members)
+		group.members = types.NewOptional(members)
+	}
+}
+
+// WithGroupBackend changes how the group is read and changed, the shadow-utils
+// backend is used by default.
+func WithGroupBackend(backend AccountBackend) GroupOption {
+	return func(group *Group) {
+		logger.Global().Info("specifying group backend", "name", group.name, "backend", fmt.Sprintf("%T", backend))
+		group.backend = backend
+	}
+}
+
+func (g *Group) Id() string {
+	return g.name
+}
+
+func (g *Group) Check() ([]Correction, error) {
+	entry, err := g.backend.lookupGroup(g.name)
+
+	if errors.Is(err, user.UnknownGroupError(g.name)) {
+		logger.Global().Warn("group does not exist", "name", g.name)
+		corrections := []Correction{
+			g.create,
+			g.setMembers,
+		}
+		return corrections, ErrUnalignedResource
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup group details: %w", err)
+	}
+
+	corrections := make([]Correction, 0)
+
+	if g.gid.Ok() && entry.gid != g.gid.Value() {
+		logger.Global().Warn(
+			"group has wrong gid", "name", g.name,
+			"gid.actual", entry.gid, "gid.target", g.gid.Value(),
+		)
+		corrections = append(corrections, g.changeGid)
+	}
+
+	if g.members.Ok() {
+		actual, target := slices.Clone(entry.members), slices.Clone(g.members.Value())
+		slices.Sort(actual)
+		slices.Sort(target)
+
+		if !slices.Equal(actual, target) {
+			logger.Global().Warn(
+				"group has wrong members", "name", g.name,
+				"members.actual", actual, "members.target", target,
+			)
+			corrections = append(corrections, g.setMembers)
+		}
+	}
+
+	if len(corrections) > 0 {
+		return corrections, ErrUnalignedResource
+	}
+
+	return nil, nil
+}
+
+// Watch re-checks the group whenever its entry in the account databases
+// changes (see subscribeAccounts).
+func (g *Group) Watch(
+	ctx context.Context,
+	correctionsCh chan<- []Correction,
+	errCh chan<- error,
+) {
+	err := check(g, correctionsCh)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	subscription, unsubscribe, err := subscribeAccounts(g.backend, groupAccount(g.name))
+	if err != nil {
+		errCh <- err
+		return
+	}
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			errCh <- ctx.Err()
+			return
+
+		case err := <-subscription.errs:
+			errCh <- err
+			return
+
+		case <-subscription.changes:
+			err := check(g, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}
+}
+
+func (g *Group) create() error {
+	gid := g.gid
+	if !gid.Ok() {
+		allocated, err := g.allocateGid()
+		if err != nil {
+			return err
+		}
+
+		gid = types.NewOptional(allocated)
+	}
+
+	err := g.backend.addGroup(g.name, groupChanges{gid: gid, system: g.system})
+	if err != nil {
+		return fmt.Errorf("failed to create group: %w", err)
+	}
+
+	return nil
+}
+
+func (g *Group) allocateGid() (int, error) {
+	gids := regularIds
+	if g.system {
+		gids = systemIds
+	}
+	if g.gids.Ok() {
+		gids = g.gids.Value()
+	}
+
+	return allocateGroupId(g.backend, g.name, gids, -1)
+}
+
+func (g *Group) changeGid() error {
+	if !g.gid.Ok() {
+		return nil
+	}
+
+	err := g.backend.modifyGroup(g.name, groupChanges{gid: g.gid})
+	if err != nil {
+		return fmt.Errorf("failed to change group's gid: %w", err)
+	}
+
+	return nil
+}
+
+func (g *Group) setMembers() error {
+	if !g.members.Ok() {
+		return nil
+	}
+
+	err := g.backend.modifyGroup(g.name, groupChanges{members: g.members})
+	if err != nil {
+		return fmt.Errorf("failed to set group's members: %w", err)
+	}
+
+	return nil
+}
+
+// allocateGroupId allocates a gid in the range, the preferred one (e.g., the
+// uid of the user a private group is created for) if it's free.
+func allocateGroupId(backend AccountBackend, name string, gids idRange, preferred int) (int, error) {
+	entries, err := backend.listGroups()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list groups: %w", err)
+	}
+
+	used := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		used = append(used, entry.gid)
+	}
+
+	if preferred >= gids.min && preferred <= gids.max && !slices.Contains(used, preferred) {
+		return preferred, nil
+	}
+
+	gid, err := allocateId(name, gids, used)
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate gid: %w", err)
+	}
+
+	return gid, nil
+}
@@ -0,0 +1,83 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/scherepiuk/align/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGroupUnit(t *testing.T) {
+	t.Run("new group without options", func(t *testing.T) {
+		group := NewGroup("testing")
+
+		assert.Equal(t, "testing", group.name)
+		assert.Equal(t, "testing", group.Id())
+		assert.Equal(t, types.Optional[int]{}, group.gid)
+		assert.False(t, group.system)
+		assert.Equal(t, types.Optional[[]string]{}, group.members)
+	})
+
+	t.Run("new group with multiple options", func(t *testing.T) {
+		group := NewGroup("testing", WithGroupGid(42069), WithSystemGroup(), WithMembers("first", "second"))
+
+		assert.Equal(t, "testing", group.name)
+		assert.Equal(t, "testing", group.Id())
+		assert.Equal(t, types.NewOptional(42069), group.gid)
+		assert.True(t, group.system)
+		assert.Equal(t, types.NewOptional([]string{"first", "second"}), group.members)
+	})
+}
+
+func TestGroupLinkAccountsUnit(t *testing.T) {
+	t.Run("listed user keeps group", func(t *testing.T) {
+		user := NewUser("deployer", WithGroups("users"), WithMembership(MembershipExact))
+		group := NewGroup("docker", WithMembers("deployer"))
+
+		assert.NoError(t, LinkAccounts(user, group))
+		assert.True(t, user.claimed("docker"))
+	})
+
+	t.Run("user left out of members can't be linked", func(t *testing.T) {
+		user := NewUser("deployer", WithGroups("docker"))
+		group := NewGroup("docker", WithMembers("admin"))
+
+		err := LinkAccounts(user, group)
+		assert.ErrorContains(t, err, "user deployer is in group docker, whose members leave it out")
+	})
+
+	t.Run("group membership disagreeing with members can't be linked", func(t *testing.T) {
+		group := NewGroup("docker", WithMembers("admin", "deployer"))
+
+		assert.NoError(t, LinkAccounts(group, NewGroupMembership("docker", WithGroupMembers("admin"))))
+
+		err := LinkAccounts(group, NewGroupMembership("docker", WithGroupMembers("intruder")))
+		assert.ErrorContains(t, err, "group membership of docker disagrees with the members of the group")
+
+		membership := NewGroupMembership("docker", WithGroupMembers("admin"), WithGroupMembershipMode(MembershipExact))
+		err = LinkAccounts(group, membership)
+		assert.ErrorContains(t, err, "group membership of docker disagrees with the members of the group")
+	})
+}
+
+func TestParseGroupEntryUnit(t *testing.T) {
+	t.Run("group entry without members", func(t *testing.T) {
+		entry, err := parseGroupEntry("wheel:x:10:")
+
+		assert.NoError(t, err)
+		assert.Equal(t, groupEntry{name: "wheel", gid: 10, members: []string{}}, entry)
+	})
+
+	t.Run("group entry with members", func(t *testing.T) {
+		entry, err := parseGroupEntry("docker:x:999:first,second")
+
+		assert.NoError(t, err)
+		assert.Equal(t, groupEntry{name: "docker", gid: 999, members: []string{"first", "second"}}, entry)
+	})
+
+	t.Run("malformed group entry", func(t *testing.T) {
+		_, err := parseGroupEntry("docker:x:999")
+
+		assert.ErrorContains(t, err, "malformed group entry")
+	})
+}
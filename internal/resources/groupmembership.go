@@ -0,0 +1,212 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/scherepiuk/align/internal/logger"
+)
+
+// GroupMembership manages the members of a group it does not own, including
+// the users align does not create. The members are added (and, in the exact
+// mode, removed) one by one, so the other members are left alone.
+//
+// A User in the exact mode (see WithMembership) leaves the group alone while
+// it is one of the members here, once the two are linked (see
+// WithManagedMembers and LinkAccounts). An exact GroupMembership can't be
+// linked with a User listing the group (see WithGroups) but left out of the
+// members.
+type GroupMembership struct {
+	BaseDependant
+	group      string
+	members    []string
+	membership Membership
+
+	backend AccountBackend
+}
+
+func NewGroupMembership(group string, opts ...GroupMembershipOption) *GroupMembership {
+	membership := &GroupMembership{group: group, backend: NewShadowUtilsBackend()}
+
+	for _, opt := range opts {
+		opt(membership)
+	}
+
+	return membership
+}
+
+type GroupMembershipOption func(membership *GroupMembership)
+
+func WithGroupMembers(members ...string) GroupMembershipOption {
+	return func(membership *GroupMembership) {
+		logger.Global().Info("specifying group membership members", "group", membership.group, "members", members)
+		membership.members = append(membership.members, members...)
+	}
+}
+
+// WithManagedMembers adds the managed users as members and makes them
+// dependencies, so they exist before they are added. The users are linked
+// right away, so they leave the group alone in turn (see LinkAccounts).
+func WithManagedMembers(users ...*User) GroupMembershipOption {
+	return func(membership *GroupMembership) {
+		for _, user := range users {
+			WithGroupMembers(user.name)(membership)
+			membership.SetDependencies(append(membership.Dependencies(), user)...)
+			user.claim(membership.group)
+		}
+	}
+}
+
+// WithGroupMembershipMode makes the members the only ones allowed in the
+// exact mode, the additive one is used by default.
+func WithGroupMembershipMode(mode Membership) GroupMembershipOption {
+	return func(membership *GroupMembership) {
+		logger.Global().Info("specifying group membership mode", "group", membership.group, "membership", mode)
+		membership.membership = mode
+	}
+}
+
+// WithGroupMembershipBackend changes how the group is read and changed, the
+// shadow-utils backend is used by default.
+func WithGroupMembershipBackend(backend AccountBackend) GroupMembershipOption {
+	return func(membership *GroupMembership) {
+		logger.Global().Info(
+			"specifying group membership backend", "group", membership.group,
+			"backend", fmt.Sprintf("%T", backend),
+		)
+		membership.backend = backend
+	}
+}
+
+func (m *GroupMembership) Id() string {
+	return "group-membership:" + m.group
+}
+
+func (m *GroupMembership) Check() ([]Correction, error) {
+	missing, extra, err := m.drift()
+	if err != nil {
+		return nil, err
+	}
+
+	corrections := make([]Correction, 0)
+
+	if len(missing) > 0 {
+		logger.Global().Warn("group lacks members", "group", m.group, "members", missing)
+		corrections = append(corrections, m.addMembers)
+	}
+
+	if len(extra) > 0 {
+		logger.Global().Warn("group has extra members", "group", m.group, "members", extra)
+		corrections = append(corrections, m.removeMembers)
+	}
+
+	if len(corrections) > 0 {
+		return corrections, ErrUnalignedResource
+	}
+
+	return nil, nil
+}
+
+// Watch re-checks the group whenever the account databases change (see
+// subscribeAccounts).
+func (m *GroupMembership) Watch(
+	ctx context.Context,
+	correctionsCh chan<- []Correction,
+	errCh chan<- error,
+) {
+	err := check(m, correctionsCh)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	subscription, unsubscribe, err := subscribeAccounts(m.backend, anyAccount)
+	if err != nil {
+		errCh <- err
+		return
+	}
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			errCh <- ctx.Err()
+			return
+
+		case err := <-subscription.errs:
+			errCh <- err
+			return
+
+		case <-subscription.changes:
+			err := check(m, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}
+}
+
+// drift returns the members that have to be added to the group, and the ones
+// that have to be removed from it (always none in the additive mode).
+func (m *GroupMembership) drift() ([]string, []string, error) {
+	entry, err := m.backend.lookupGroup(m.group)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lookup group: %w", err)
+	}
+
+	missing := make([]string, 0)
+	for _, member := range m.members {
+		if !slices.Contains(entry.members, member) {
+			missing = append(missing, member)
+		}
+	}
+
+	extra := make([]string, 0)
+	if m.membership == MembershipExact {
+		for _, member := range entry.members {
+			if !slices.Contains(m.members, member) {
+				extra = append(extra, member)
+			}
+		}
+	}
+
+	return missing, extra, nil
+}
+
+func (m *GroupMembership) addMembers() error {
+	missing, _, err := m.drift()
+	if err != nil {
+		return err
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	err = m.backend.modifyGroup(m.group, groupChanges{addMembers: missing})
+	if err != nil {
+		return fmt.Errorf("failed to add group's members: %w", err)
+	}
+
+	return nil
+}
+
+func (m *GroupMembership) removeMembers() error {
+	_, extra, err := m.drift()
+	if err != nil {
+		return err
+	}
+
+	if len(extra) == 0 {
+		return nil
+	}
+
+	err = m.backend.modifyGroup(m.group, groupChanges{removeMembers: extra})
+	if err != nil {
+		return fmt.Errorf("failed to remove group's members: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupMembershipIntegration(t *testing.T) {
+	t.Run("members are added and removed", func(t *testing.T) {
+		root := testAccountsRoot(t)
+		backend := NewNativeBackend(root)
+
+		group := NewGroup("docker", WithGroupGid(998), WithMembers("second", "third"), WithGroupBackend(backend))
+		assert.NoError(t, group.create())
+		assert.NoError(t, group.setMembers())
+
+		membership := NewGroupMembership("docker", WithGroupMembers("first"), WithGroupMembershipBackend(backend))
+
+		actual, err := membership.Check()
+		assertCorrections(t, []Correction{membership.addMembers}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, membership.addMembers())
+		assertAccountsFile(t, root, groupPath, "root:x:0:\nwheel:x:10:first\nusers:x:100:\ndocker:x:998:second,third,first\n")
+
+		corrections, err := membership.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		membership = NewGroupMembership(
+			"docker",
+			WithGroupMembers("first", "third"),
+			WithGroupMembershipMode(MembershipExact),
+			WithGroupMembershipBackend(backend),
+		)
+
+		actual, err = membership.Check()
+		assertCorrections(t, []Correction{membership.removeMembers}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, membership.removeMembers())
+		assertAccountsFile(t, root, groupPath, "root:x:0:\nwheel:x:10:first\nusers:x:100:\ndocker:x:998:third,first\n")
+		assertAccountsFile(t, root, gshadowPath, "root:*::\nwheel:!::first\nusers:!::\ndocker:!::third,first\n")
+
+		corrections, err = membership.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("exact user keeps claimed groups", func(t *testing.T) {
+		root := testAccountsRoot(t)
+		backend := NewNativeBackend(root)
+
+		group := NewGroup("audit", WithGroupGid(997), WithGroupBackend(backend))
+		assert.NoError(t, group.create())
+
+		user := NewUser(
+			"auditor",
+			WithUid(42071),
+			WithGid(1000),
+			WithGroups("wheel", "users"),
+			WithMembership(MembershipExact),
+			WithUserBackend(backend),
+		)
+		assert.NoError(t, user.create())
+
+		membership := NewGroupMembership("audit", WithManagedMembers(user), WithGroupMembershipBackend(backend))
+		assert.Equal(t, []Resource{user}, membership.Dependencies())
+		assert.NoError(t, membership.addMembers())
+
+		corrections, err := user.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		user = NewUser(
+			"auditor",
+			WithUid(42071),
+			WithGid(1000),
+			WithGroups("users"),
+			WithMembership(MembershipExact),
+			WithUserBackend(backend),
+		)
+		membership = NewGroupMembership(
+			"audit",
+			WithManagedMembers(user),
+			WithGroupMembershipMode(MembershipExact),
+			WithGroupMembershipBackend(backend),
+		)
+
+		actual, err := user.Check()
+		assertCorrections(t, []Correction{user.setGroups}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, user.setGroups())
+		assertAccountsFile(t, root, groupPath, "root:x:0:\nwheel:x:10:first\nusers:x:100:auditor\naudit:x:997:auditor\n")
+
+		corrections, err = membership.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		corrections, err = user.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("user not managed by membership loses group", func(t *testing.T) {
+		root := testAccountsRoot(t)
+		backend := NewNativeBackend(root)
+
+		group := NewGroup("audit", WithGroupGid(997), WithGroupBackend(backend))
+		assert.NoError(t, group.create())
+
+		user := NewUser(
+			"auditor",
+			WithUid(42071),
+			WithGid(1000),
+			WithGroups("audit"),
+			WithMembership(MembershipExact),
+			WithUserBackend(backend),
+		)
+		assert.NoError(t, user.create())
+
+		// NOTE: Memberships of the other configurations are not claims.
+		NewGroupMembership("audit", WithManagedMembers(NewUser("auditor")), WithGroupMembershipBackend(backend))
+
+		user = NewUser("auditor", WithUid(42071), WithGid(1000), WithGroups("users"), WithMembership(MembershipExact), WithUserBackend(backend))
+
+		actual, err := user.Check()
+		assertCorrections(t, []Correction{user.setGroups}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, user.setGroups())
+		assertAccountsFile(t, root, groupPath, "root:x:0:\nwheel:x:10:first\nusers:x:100:auditor\naudit:x:997:\n")
+	})
+
+	t.Run("linked user keeps group listed by name", func(t *testing.T) {
+		root := testAccountsRoot(t)
+		backend := NewNativeBackend(root)
+
+		group := NewGroup("docker", WithGroupGid(998), WithGroupBackend(backend))
+		assert.NoError(t, group.create())
+
+		user := NewUser("deployer", WithUid(42071), WithGid(1000), WithGroups("users"), WithMembership(MembershipExact), WithUserBackend(backend))
+		assert.NoError(t, user.create())
+
+		membership := NewGroupMembership("docker", WithGroupMembers("deployer"), WithGroupMembershipBackend(backend))
+		assert.NoError(t, LinkAccounts(user, membership))
+		assert.NoError(t, membership.addMembers())
+
+		corrections, err := user.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		assertAccountsFile(t, root, groupPath, "root:x:0:\nwheel:x:10:first\nusers:x:100:deployer\ndocker:x:998:deployer\n")
+	})
+
+	t.Run("exact membership leaving out user listing group can't be linked", func(t *testing.T) {
+		user := NewUser("deployer", WithGroups("docker"))
+		membership := NewGroupMembership("docker", WithGroupMembers("admin"), WithGroupMembershipMode(MembershipExact))
+
+		err := LinkAccounts(user, membership)
+		assert.ErrorContains(t, err, "user deployer is in group docker, whose exact group membership leaves it out")
+
+		membership = NewGroupMembership("docker", WithGroupMembers("admin"))
+		assert.NoError(t, LinkAccounts(user, membership))
+	})
+
+	t.Run("missing group", func(t *testing.T) {
+		membership := NewGroupMembership(
+			"missing",
+			WithGroupMembers("first"),
+			WithGroupMembershipBackend(NewNativeBackend(testAccountsRoot(t))),
+		)
+
+		corrections, err := membership.Check()
+		assert.Nil(t, corrections)
+		assert.ErrorContains(t, err, "unknown group missing")
+	})
+}
@@ -0,0 +1,43 @@
+package resources
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+)
+
+// idRange is an inclusive range of uids or gids (see UID_MIN and friends in
+// login.defs(5)).
+type idRange struct {
+	min int
+	max int
+}
+
+var (
+	regularIds = idRange{min: 1000, max: 59999}
+	systemIds  = idRange{min: 100, max: 999}
+)
+
+// allocateId picks a free id for the name. The starting point in the range is
+// the hash of the name, so the same name gets the same id across runs and
+// hosts unless it collides, in which case the following ids are probed.
+func allocateId(name string, ids idRange, used []int) (int, error) {
+	if ids.max < ids.min {
+		return 0, fmt.Errorf("invalid id range: %d-%d", ids.min, ids.max)
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+
+	size := ids.max - ids.min + 1
+	start := int(hash.Sum32() % uint32(size))
+
+	for i := 0; i < size; i++ {
+		id := ids.min + (start+i)%size
+		if !slices.Contains(used, id) {
+			return id, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no free ids in range: %d-%d", ids.min, ids.max)
+}
@@ -33,6 +33,25 @@ type Dependant interface {
 
 var ErrUnalignedResource = errors.New("unaligned resource")
 
+// Ensure tells whether the resource is expected to exist or not.
+type Ensure int
+
+const (
+	EnsurePresent Ensure = iota
+	EnsureAbsent
+)
+
+func (e Ensure) String() string {
+	switch e {
+	case EnsurePresent:
+		return "present"
+	case EnsureAbsent:
+		return "absent"
+	default:
+		return "unknown"
+	}
+}
+
 type BaseDependant struct {
 	dependencies []Resource
 }
@@ -44,3 +63,23 @@ func (d *BaseDependant) Dependencies() []Resource {
 func (d *BaseDependant) SetDependencies(dependencies ...Resource) {
 	d.dependencies = dependencies
 }
+
+// Membership tells whether the listed members (or groups) are the only ones
+// allowed, or just the ones that have to be there.
+type Membership int
+
+const (
+	MembershipAdditive Membership = iota
+	MembershipExact
+)
+
+func (m Membership) String() string {
+	switch m {
+	case MembershipAdditive:
+		return "additive"
+	case MembershipExact:
+		return "exact"
+	default:
+		return "unknown"
+	}
+}
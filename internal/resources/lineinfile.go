@@ -0,0 +1,278 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/scherepiuk/align/internal/logger"
+	"github.com/scherepiuk/align/internal/types"
+)
+
+const defaultLineInFileMode = os.FileMode(0o644)
+
+// LineInFile manages a single line of a file, leaving the rest of it alone.
+// It's meant for the files that are shared with other tools or people (e.g.,
+// /etc/ssh/sshd_config), where owning the whole file is not an option.
+type LineInFile struct {
+	BaseDependant
+	path         string
+	line         string
+	regexp       types.Optional[*regexp.Regexp]
+	insertBefore types.Optional[*regexp.Regexp]
+	insertAfter  types.Optional[*regexp.Regexp]
+	ensure       Ensure
+	backup       types.Optional[*Backup]
+
+	// err is the error of the options (e.g., an invalid regular expression),
+	// returned by NewLineInFile.
+	err error
+}
+
+func NewLineInFile(path, line string, opts ...LineInFileOption) (*LineInFile, error) {
+	lineInFile := &LineInFile{path: path, line: line}
+
+	for _, opt := range opts {
+		opt(lineInFile)
+	}
+
+	if lineInFile.err != nil {
+		return nil, lineInFile.err
+	}
+
+	// NOTE: Once replaced, a line the regular expression does not match would
+	// be missing, and inserted again on every check.
+	pattern := lineInFile.regexp
+	if lineInFile.ensure == EnsurePresent && pattern.Ok() && !pattern.Value().MatchString(line) {
+		return nil, fmt.Errorf("regexp %q does not match line %q", pattern.Value(), line)
+	}
+
+	return lineInFile, nil
+}
+
+type LineInFileOption func(lineInFile *LineInFile)
+
+// WithLineRegexp identifies the managed line by the regular expression
+// instead of its exact text, which allows to replace lines that drifted. The
+// regular expression has to match the line itself as well.
+// If multiple lines match, the first one is replaced and the others are
+// removed, so the file ends up with a single one.
+func WithLineRegexp(pattern string) LineInFileOption {
+	return func(lineInFile *LineInFile) {
+		logger.Global().Info("specifying line regexp", "path", lineInFile.path, "regexp", pattern)
+		lineInFile.regexp = lineInFile.compile(pattern)
+	}
+}
+
+// WithInsertBefore inserts the missing line before the first line matching
+// the regular expression. The line is appended if nothing matches.
+func WithInsertBefore(pattern string) LineInFileOption {
+	return func(lineInFile *LineInFile) {
+		logger.Global().Info("specifying line anchor", "path", lineInFile.path, "before", pattern)
+		lineInFile.insertBefore = lineInFile.compile(pattern)
+		lineInFile.insertAfter = types.Optional[*regexp.Regexp]{}
+	}
+}
+
+// WithInsertAfter inserts the missing line after the last line matching
+// the regular expression. The line is appended if nothing matches.
+func WithInsertAfter(pattern string) LineInFileOption {
+	return func(lineInFile *LineInFile) {
+		logger.Global().Info("specifying line anchor", "path", lineInFile.path, "after", pattern)
+		lineInFile.insertAfter = lineInFile.compile(pattern)
+		lineInFile.insertBefore = types.Optional[*regexp.Regexp]{}
+	}
+}
+
+// compile compiles the pattern of an option, the error is kept for
+// NewLineInFile.
+func (l *LineInFile) compile(pattern string) types.Optional[*regexp.Regexp] {
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		l.err = errors.Join(l.err, fmt.Errorf("invalid regexp %q: %w", pattern, err))
+		return types.Optional[*regexp.Regexp]{}
+	}
+
+	return types.NewOptional(compiled)
+}
+
+func WithLineEnsure(ensure Ensure) LineInFileOption {
+	return func(lineInFile *LineInFile) {
+		logger.Global().Info("specifying line ensure", "path", lineInFile.path, "ensure", ensure)
+		lineInFile.ensure = ensure
+	}
+}
+
+// WithLineBackup saves the file before the line is changed.
+func WithLineBackup(backup *Backup) LineInFileOption {
+	return func(lineInFile *LineInFile) {
+		logger.Global().Info("specifying line backup", "path", lineInFile.path, "dir", backup.dir)
+		lineInFile.backup = types.NewOptional(backup)
+	}
+}
+
+func (l *LineInFile) Id() string {
+	if l.regexp.Ok() {
+		return fmt.Sprintf("%s:%s", l.path, l.regexp.Value().String())
+	}
+
+	return fmt.Sprintf("%s:%s", l.path, l.line)
+}
+
+func (l *LineInFile) Check() ([]Correction, error) {
+	lines, err := readLines(l.path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		if l.ensure == EnsureAbsent {
+			return nil, nil
+		}
+
+		logger.Global().Warn("file does not exist", "path", l.path)
+		return []Correction{l.changeLine}, ErrUnalignedResource
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	_, drift := l.apply(lines)
+	if drift != "" {
+		logger.Global().Warn(drift, "path", l.path, "line", l.line)
+		return []Correction{l.changeLine}, ErrUnalignedResource
+	}
+
+	return nil, nil
+}
+
+func (l *LineInFile) Watch(
+	ctx context.Context,
+	correctionsCh chan<- []Correction,
+	errCh chan<- error,
+) {
+	watchFile(ctx, l, fileWatch{path: l.path}, correctionsCh, errCh)
+}
+
+// apply returns the lines of the file with the managed line aligned, and the
+// description of the drift, which is empty if nothing had to be changed.
+func (l *LineInFile) apply(lines []string) ([]string, string) {
+	matches := make([]int, 0)
+	for i, line := range lines {
+		if l.matches(line) {
+			matches = append(matches, i)
+		}
+	}
+
+	if l.ensure == EnsureAbsent {
+		if len(matches) == 0 {
+			return lines, ""
+		}
+
+		lines = slices.DeleteFunc(slices.Clone(lines), l.matches)
+		return lines, "line is present but should be absent"
+	}
+
+	// NOTE: Only the lines matching the regular expression can differ, the
+	// copies of the exact line are left alone.
+	if len(matches) > 0 {
+		first := matches[0]
+		duplicated := l.regexp.Ok() && len(matches) > 1
+
+		if lines[first] == l.line && !duplicated {
+			return lines, ""
+		}
+
+		drift := "line has wrong content"
+		if lines[first] == l.line {
+			drift = "line is duplicated"
+		}
+
+		aligned := make([]string, 0, len(lines))
+		for i, line := range lines {
+			switch {
+			case i == first:
+				aligned = append(aligned, l.line)
+			case !duplicated || !slices.Contains(matches, i):
+				aligned = append(aligned, line)
+			}
+		}
+
+		return aligned, drift
+	}
+
+	index := len(lines)
+
+	if l.insertBefore.Ok() {
+		i := slices.IndexFunc(lines, l.insertBefore.Value().MatchString)
+		if i != -1 {
+			index = i
+		}
+	}
+
+	if l.insertAfter.Ok() {
+		for i, line := range lines {
+			if l.insertAfter.Value().MatchString(line) {
+				index = i + 1
+			}
+		}
+	}
+
+	return slices.Insert(slices.Clone(lines), index, l.line), "line is missing"
+}
+
+func (l *LineInFile) matches(line string) bool {
+	if l.regexp.Ok() {
+		return l.regexp.Value().MatchString(line)
+	}
+
+	return line == l.line
+}
+
+func (l *LineInFile) changeLine() error {
+	lines, err := readLines(l.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	lines, drift := l.apply(lines)
+	if drift == "" {
+		return nil
+	}
+
+	err = saveBackup(l.backup, l.Id(), l.path)
+	if err != nil {
+		return err
+	}
+
+	err = writeLines(l.path, lines)
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
+
+func readLines(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(content) == 0 {
+		return []string{}, nil
+	}
+
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n"), nil
+}
+
+func writeLines(path string, lines []string) error {
+	content := ""
+	if len(lines) > 0 {
+		content = strings.Join(lines, "\n") + "\n"
+	}
+
+	return overwriteFile(path, []byte(content), defaultLineInFileMode)
+}
@@ -0,0 +1,182 @@
+package resources
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineInFileApplyUnit(t *testing.T) {
+	type testCase struct {
+		name          string
+		line          string
+		opts          []LineInFileOption
+		lines         []string
+		expectedLines []string
+		expectedDrift string
+	}
+
+	testCases := []testCase{
+		{
+			name:          "line is present",
+			line:          "b",
+			lines:         []string{"a", "b", "c"},
+			expectedLines: []string{"a", "b", "c"},
+			expectedDrift: "",
+		},
+		{
+			name:          "line is missing",
+			line:          "d",
+			lines:         []string{"a", "b", "c"},
+			expectedLines: []string{"a", "b", "c", "d"},
+			expectedDrift: "line is missing",
+		},
+		{
+			name:          "line is missing in empty file",
+			line:          "a",
+			lines:         []string{},
+			expectedLines: []string{"a"},
+			expectedDrift: "line is missing",
+		},
+		{
+			name:          "line has wrong content",
+			line:          "PermitRootLogin no",
+			opts:          []LineInFileOption{WithLineRegexp(`^#?PermitRootLogin\s`)},
+			lines:         []string{"Port 22", "PermitRootLogin yes", "UsePAM yes"},
+			expectedLines: []string{"Port 22", "PermitRootLogin no", "UsePAM yes"},
+			expectedDrift: "line has wrong content",
+		},
+		{
+			name:          "first matching line is replaced and others are removed",
+			line:          "PermitRootLogin no",
+			opts:          []LineInFileOption{WithLineRegexp(`^#?PermitRootLogin\s`)},
+			lines:         []string{"#PermitRootLogin yes", "Port 22", "PermitRootLogin yes"},
+			expectedLines: []string{"PermitRootLogin no", "Port 22"},
+			expectedDrift: "line has wrong content",
+		},
+		{
+			name:          "matching duplicates are removed",
+			line:          "PermitRootLogin no",
+			opts:          []LineInFileOption{WithLineRegexp(`^#?PermitRootLogin\s`)},
+			lines:         []string{"PermitRootLogin no", "PermitRootLogin yes"},
+			expectedLines: []string{"PermitRootLogin no"},
+			expectedDrift: "line is duplicated",
+		},
+		{
+			name:          "copies of exact line are left alone",
+			line:          "b",
+			lines:         []string{"b", "a", "b"},
+			expectedLines: []string{"b", "a", "b"},
+			expectedDrift: "",
+		},
+		{
+			name:          "line is inserted before anchor",
+			line:          "b",
+			opts:          []LineInFileOption{WithInsertBefore(`^c$`)},
+			lines:         []string{"a", "c", "c"},
+			expectedLines: []string{"a", "b", "c", "c"},
+			expectedDrift: "line is missing",
+		},
+		{
+			name:          "line is inserted after anchor",
+			line:          "b",
+			opts:          []LineInFileOption{WithInsertAfter(`^a$`)},
+			lines:         []string{"a", "a", "c"},
+			expectedLines: []string{"a", "a", "b", "c"},
+			expectedDrift: "line is missing",
+		},
+		{
+			name:          "line is appended if anchor does not match",
+			line:          "b",
+			opts:          []LineInFileOption{WithInsertAfter(`^x$`)},
+			lines:         []string{"a", "c"},
+			expectedLines: []string{"a", "c", "b"},
+			expectedDrift: "line is missing",
+		},
+		{
+			name:          "line should be absent",
+			line:          "",
+			opts:          []LineInFileOption{WithLineRegexp(`^export `), WithLineEnsure(EnsureAbsent)},
+			lines:         []string{"export A=1", "B=2", "export C=3"},
+			expectedLines: []string{"B=2"},
+			expectedDrift: "line is present but should be absent",
+		},
+		{
+			name:          "line is absent",
+			line:          "a",
+			opts:          []LineInFileOption{WithLineEnsure(EnsureAbsent)},
+			lines:         []string{"b", "c"},
+			expectedLines: []string{"b", "c"},
+			expectedDrift: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lineInFile, err := NewLineInFile("/tmp/testing", tc.line, tc.opts...)
+			assert.NoError(t, err)
+
+			actualLines, actualDrift := lineInFile.apply(tc.lines)
+
+			assert.Equal(t, tc.expectedLines, actualLines)
+			assert.Equal(t, tc.expectedDrift, actualDrift)
+		})
+	}
+}
+
+func TestLineInFileCheckIntegration(t *testing.T) {
+	t.Run("file does not exist", func(t *testing.T) {
+		path := testFilePath()
+
+		lineInFile, err := NewLineInFile(path, "a")
+		assert.NoError(t, err)
+		expected := []Correction{lineInFile.changeLine}
+
+		actual, err := lineInFile.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+	})
+
+	t.Run("line has wrong content", func(t *testing.T) {
+		path := testFilePath()
+
+		err := os.WriteFile(path, []byte("a\nkey=actual\nc\n"), 0o664)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(path) })
+
+		lineInFile, err := NewLineInFile(path, "key=target", WithLineRegexp(`^key=`))
+		assert.NoError(t, err)
+		expected := []Correction{lineInFile.changeLine}
+
+		actual, err := lineInFile.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		err = lineInFile.changeLine()
+		assert.NoError(t, err)
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "a\nkey=target\nc\n", string(content))
+
+		corrections, err := lineInFile.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+}
+
+func TestNewLineInFileUnit(t *testing.T) {
+	_, err := NewLineInFile("/tmp/testing", "a", WithLineRegexp(`(`), WithInsertAfter(`[`))
+	assert.ErrorContains(t, err, "invalid regexp \"(\"")
+	assert.ErrorContains(t, err, "invalid regexp \"[\"")
+
+	_, err = NewLineInFile("/tmp/testing", "PermitRootLogin no", WithLineRegexp(`^#PermitRootLogin`))
+	assert.ErrorContains(t, err, "regexp \"^#PermitRootLogin\" does not match line \"PermitRootLogin no\"")
+
+	_, err = NewLineInFile("/tmp/testing", "", WithLineRegexp(`^#PermitRootLogin`), WithLineEnsure(EnsureAbsent))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,343 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/scherepiuk/align/internal/logger"
+)
+
+const (
+	defaultPackagePolling = 30 * time.Second
+	// packageQueryTTL is how long the batched query is reused for, long
+	// enough for all the packages checked at once to share it.
+	packageQueryTTL = 2 * time.Second
+)
+
+var (
+	packageNameRegexp    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9+._-]*$`)
+	packageVersionRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9+.~_:-]*$`)
+)
+
+// PackageState tells whether the package is expected to be installed, and
+// whether it has to be kept up to date.
+type PackageState int
+
+const (
+	PackagePresent PackageState = iota
+	PackageAbsent
+	PackageLatest
+)
+
+func (s PackageState) String() string {
+	switch s {
+	case PackagePresent:
+		return "present"
+	case PackageAbsent:
+		return "absent"
+	case PackageLatest:
+		return "latest"
+	default:
+		return "unknown"
+	}
+}
+
+// PackageManager queries and changes the installed packages. There are three
+// of them: apt (see NewAptPackageManager), dnf (see NewDnfPackageManager) and
+// apk (see NewApkPackageManager), the one available on the host is used by
+// default.
+//
+// The packages sharing one of these managers are queried together, since the
+// managers are slow to start. Other implementations are queried for each
+// package on its own.
+type PackageManager interface {
+	// Installed returns the versions of the packages that are installed,
+	// the others are missing from the result.
+	Installed(names []string) (map[string]string, error)
+	// Upgradable returns the newer versions of the packages that can be
+	// upgraded, the others are missing from the result.
+	Upgradable(names []string) (map[string]string, error)
+
+	// Install installs the version of the package, or the newest one if
+	// the version is empty.
+	Install(name, version string) error
+	Upgrade(name string) error
+	Remove(name string) error
+}
+
+// batchingPackageManager is a manager that batches the queries of all the
+// packages using it (see packageManagerBase).
+type batchingPackageManager interface {
+	batched() *packageBatch
+}
+
+// packageBatch is the packages of a manager, which are queried at once.
+type packageBatch struct {
+	mu     sync.Mutex
+	names  []string
+	latest []string // the names of the packages to be kept up to date
+
+	installedVersions  map[string]string
+	upgradableVersions map[string]string
+	queriedAt          time.Time
+}
+
+// batchPackage adds the package to the batch of the manager, or to a batch of
+// its own if the manager does not batch the queries.
+func batchPackage(manager PackageManager, name string, state PackageState) *packageBatch {
+	batch := &packageBatch{}
+	if batching, ok := manager.(batchingPackageManager); ok {
+		batch = batching.batched()
+	}
+
+	batch.mu.Lock()
+	defer batch.mu.Unlock()
+
+	if !slices.Contains(batch.names, name) {
+		batch.names = append(batch.names, name)
+	}
+
+	if state == PackageLatest && !slices.Contains(batch.latest, name) {
+		batch.latest = append(batch.latest, name)
+	}
+
+	batch.queriedAt = time.Time{}
+
+	return batch
+}
+
+// query returns the installed and the upgradable versions of all the packages
+// in the batch, queried anew once the previous result is too old.
+func (b *packageBatch) query(manager PackageManager) (map[string]string, map[string]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if time.Since(b.queriedAt) < packageQueryTTL {
+		return b.installedVersions, b.upgradableVersions, nil
+	}
+
+	installed, err := manager.Installed(b.names)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query installed packages: %w", err)
+	}
+
+	upgradable := map[string]string{}
+	if len(b.latest) > 0 {
+		upgradable, err = manager.Upgradable(b.latest)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to query upgradable packages: %w", err)
+		}
+	}
+
+	b.installedVersions, b.upgradableVersions, b.queriedAt = installed, upgradable, time.Now()
+
+	return installed, upgradable, nil
+}
+
+// invalidate makes the next query run anew, e.g., after a package has been
+// installed.
+func (b *packageBatch) invalidate() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.queriedAt = time.Time{}
+}
+
+// Package manages a package of the system package manager.
+type Package struct {
+	BaseDependant
+	name    string
+	version string
+	state   PackageState
+
+	manager      PackageManager
+	batch        *packageBatch
+	pollInterval time.Duration
+}
+
+func NewPackage(name string, opts ...PackageOption) (*Package, error) {
+	pkg := &Package{name: name, pollInterval: defaultPackagePolling}
+
+	for _, opt := range opts {
+		opt(pkg)
+	}
+
+	if !packageNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid package name: %q", name)
+	}
+
+	if pkg.version != "" && !packageVersionRegexp.MatchString(pkg.version) {
+		return nil, fmt.Errorf("invalid package version of %s: %q", name, pkg.version)
+	}
+
+	if pkg.state != PackagePresent && pkg.version != "" {
+		return nil, fmt.Errorf("package version can only be pinned when present: %s", name)
+	}
+
+	if pkg.manager == nil {
+		pkg.manager = defaultPackageManager()
+	}
+
+	// NOTE: Without a manager (e.g., on an unsupported host), Check reports
+	// the error instead.
+	if pkg.manager != nil {
+		pkg.batch = batchPackage(pkg.manager, name, pkg.state)
+	}
+
+	return pkg, nil
+}
+
+type PackageOption func(pkg *Package)
+
+// WithPackageVersion pins the version (as the package manager spells it,
+// e.g., "1.24.0-1ubuntu1"), which is installed even if it is older than the
+// installed one.
+func WithPackageVersion(version string) PackageOption {
+	return func(pkg *Package) {
+		logger.Global().Info("specifying package version", "name", pkg.name, "version", version)
+		pkg.version = version
+	}
+}
+
+func WithPackageState(state PackageState) PackageOption {
+	return func(pkg *Package) {
+		logger.Global().Info("specifying package state", "name", pkg.name, "state", state)
+		pkg.state = state
+	}
+}
+
+// WithPackageManager changes how the package is queried and installed, the
+// manager available on the host is used by default.
+func WithPackageManager(manager PackageManager) PackageOption {
+	return func(pkg *Package) {
+		logger.Global().Info("specifying package manager", "name", pkg.name, "manager", fmt.Sprintf("%T", manager))
+		pkg.manager = manager
+	}
+}
+
+// WithPackagePolling changes how often the package is re-checked.
+func WithPackagePolling(interval time.Duration) PackageOption {
+	return func(pkg *Package) {
+		logger.Global().Info("specifying package polling", "name", pkg.name, "interval", interval)
+		pkg.pollInterval = interval
+	}
+}
+
+func (p *Package) Id() string {
+	return "package:" + p.name
+}
+
+func (p *Package) Check() ([]Correction, error) {
+	if p.manager == nil {
+		return nil, errors.New("no supported package manager found")
+	}
+
+	installed, upgradable, err := p.batch.query(p.manager)
+	if err != nil {
+		return nil, err
+	}
+
+	version, ok := installed[p.name]
+
+	if p.state == PackageAbsent {
+		if !ok {
+			return nil, nil
+		}
+
+		logger.Global().Warn("package is installed but should be absent", "name", p.name, "version", version)
+		return []Correction{p.remove}, ErrUnalignedResource
+	}
+
+	if !ok {
+		logger.Global().Warn("package is not installed", "name", p.name)
+		return []Correction{p.install}, ErrUnalignedResource
+	}
+
+	if p.version != "" && version != p.version {
+		logger.Global().Warn(
+			"package has wrong version", "name", p.name,
+			"version.actual", version, "version.target", p.version,
+		)
+		return []Correction{p.install}, ErrUnalignedResource
+	}
+
+	if newer, ok := upgradable[p.name]; p.state == PackageLatest && ok {
+		logger.Global().Warn(
+			"package is outdated", "name", p.name,
+			"version.actual", version, "version.latest", newer,
+		)
+		return []Correction{p.upgrade}, ErrUnalignedResource
+	}
+
+	return nil, nil
+}
+
+// Watch polls the package (see WithPackagePolling), the queries are batched
+// with the other packages polled at the same time.
+func (p *Package) Watch(
+	ctx context.Context,
+	correctionsCh chan<- []Correction,
+	errCh chan<- error,
+) {
+	err := check(p, correctionsCh)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			errCh <- ctx.Err()
+			return
+
+		case <-ticker.C:
+			err := check(p, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}
+}
+
+func (p *Package) install() error {
+	defer p.batch.invalidate()
+
+	err := p.manager.Install(p.name, p.version)
+	if err != nil {
+		return fmt.Errorf("failed to install package: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Package) upgrade() error {
+	defer p.batch.invalidate()
+
+	err := p.manager.Upgrade(p.name)
+	if err != nil {
+		return fmt.Errorf("failed to upgrade package: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Package) remove() error {
+	defer p.batch.invalidate()
+
+	err := p.manager.Remove(p.name)
+	if err != nil {
+		return fmt.Errorf("failed to remove package: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,373 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scherepiuk/align/internal/logger"
+)
+
+// aptUpdateInterval is how often the package lists are refreshed before the
+// upgrades are queried, apt-cache alone only knows the lists already there.
+const aptUpdateInterval = time.Hour
+
+var defaultPackageManager = sync.OnceValue(func() PackageManager {
+	switch {
+	case commandAvailable("apt-get"):
+		return NewAptPackageManager()
+	case commandAvailable("dnf"):
+		return NewDnfPackageManager()
+	case commandAvailable("apk"):
+		return NewApkPackageManager()
+	default:
+		return nil
+	}
+})
+
+func commandAvailable(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// packageManagerBase is what the managers share: the runner of the commands,
+// and the batch of the packages using the manager.
+type packageManagerBase struct {
+	runner CommandRunner
+	batch  *packageBatch
+}
+
+func newPackageManagerBase(opts ...PackageManagerOption) packageManagerBase {
+	base := packageManagerBase{runner: NewExecRunner(), batch: &packageBatch{}}
+
+	for _, opt := range opts {
+		opt(&base)
+	}
+
+	return base
+}
+
+func (b *packageManagerBase) batched() *packageBatch {
+	return b.batch
+}
+
+type PackageManagerOption func(manager *packageManagerBase)
+
+// WithPackageManagerRunner changes how the commands of the manager are run,
+// they are executed on the host by default.
+func WithPackageManagerRunner(runner CommandRunner) PackageManagerOption {
+	return func(manager *packageManagerBase) {
+		logger.Global().Info("specifying package manager runner", "runner", fmt.Sprintf("%T", runner))
+		manager.runner = runner
+	}
+}
+
+// aptPackageManager queries the packages with dpkg-query and apt-cache, and
+// changes them with apt-get.
+type aptPackageManager struct {
+	packageManagerBase
+
+	mu        sync.Mutex
+	updatedAt time.Time // when the package lists were last refreshed
+}
+
+func NewAptPackageManager(opts ...PackageManagerOption) PackageManager {
+	return &aptPackageManager{packageManagerBase: newPackageManagerBase(opts...)}
+}
+
+// Installed tolerates the exit code 1, which dpkg-query returns when some of
+// the packages are unknown to it.
+func (m *aptPackageManager) Installed(names []string) (map[string]string, error) {
+	command := Command{
+		Name: "dpkg-query",
+		Args: append([]string{"-W", "-f=${Package}\t${db:Status-Status}\t${Version}\n", "--"}, names...),
+	}
+
+	result, err := m.runner.Run(context.Background(), command)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.ExitCode > 1 {
+		return nil, fmt.Errorf("%s: exit status %d: %s", command, result.ExitCode, result.output())
+	}
+
+	versions := make(map[string]string)
+	for _, line := range strings.Split(string(result.Stdout), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) == 3 && fields[1] == "installed" {
+			versions[fields[0]] = fields[2]
+		}
+	}
+
+	return versions, nil
+}
+
+// Upgradable refreshes the package lists first (see aptUpdateInterval), so the
+// newer versions are known.
+func (m *aptPackageManager) Upgradable(names []string) (map[string]string, error) {
+	err := m.update()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := runChecked(
+		context.Background(),
+		m.runner,
+		Command{Name: "apt-cache", Args: append([]string{"policy", "--"}, names...)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]string)
+	name, installed := "", ""
+
+	for _, line := range strings.Split(string(result.Stdout), "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case !strings.HasPrefix(line, " ") && strings.HasSuffix(line, ":"):
+			name, installed = strings.TrimSuffix(line, ":"), ""
+		case strings.HasPrefix(trimmed, "Installed: "):
+			installed = strings.TrimPrefix(trimmed, "Installed: ")
+		case strings.HasPrefix(trimmed, "Candidate: "):
+			candidate := strings.TrimPrefix(trimmed, "Candidate: ")
+			if installed != "(none)" && candidate != "(none)" && candidate != installed {
+				versions[name] = candidate
+			}
+		}
+	}
+
+	return versions, nil
+}
+
+func (m *aptPackageManager) Install(name, version string) error {
+	if version != "" {
+		name += "=" + version
+	}
+
+	return m.aptGet("install", "--allow-downgrades", name)
+}
+
+func (m *aptPackageManager) Upgrade(name string) error {
+	return m.aptGet("install", "--only-upgrade", name)
+}
+
+func (m *aptPackageManager) Remove(name string) error {
+	return m.aptGet("remove", name)
+}
+
+func (m *aptPackageManager) update() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if time.Since(m.updatedAt) < aptUpdateInterval {
+		return nil
+	}
+
+	err := m.aptGet("update")
+	if err != nil {
+		return fmt.Errorf("failed to update package lists: %w", err)
+	}
+
+	m.updatedAt = time.Now()
+
+	return nil
+}
+
+func (m *aptPackageManager) aptGet(args ...string) error {
+	_, err := runChecked(context.Background(), m.runner, Command{
+		Name: "apt-get",
+		Args: append([]string{"-y", "-q"}, args...),
+		Env:  []string{"DEBIAN_FRONTEND=noninteractive"},
+	})
+
+	return err
+}
+
+// dnfPackageManager queries the installed packages with rpm, and the rest
+// with dnf.
+type dnfPackageManager struct {
+	packageManagerBase
+}
+
+func NewDnfPackageManager(opts ...PackageManagerOption) PackageManager {
+	return &dnfPackageManager{packageManagerBase: newPackageManagerBase(opts...)}
+}
+
+// Installed expects the exit code to be the number of the packages that are
+// not installed (capped at 255, as by rpm), each of them reported in place of
+// its version. Anything else rpm prints to stdout is an error, while stderr
+// is ignored, as rpm warns there (e.g., about the database) even when the
+// query succeeds.
+func (m *dnfPackageManager) Installed(names []string) (map[string]string, error) {
+	command := Command{
+		Name: "rpm",
+		Args: append([]string{"-q", "--qf", "%{NAME}\t%{VERSION}-%{RELEASE}\n", "--"}, names...),
+	}
+
+	result, err := m.runner.Run(context.Background(), command)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := 0
+	for _, line := range strings.Split(strings.TrimSuffix(string(result.Stdout), "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "package ") && strings.HasSuffix(line, " is not installed"):
+			missing++
+		case !strings.Contains(line, "\t") && line != "":
+			return nil, fmt.Errorf("%s: unexpected output: %s", command, line)
+		}
+	}
+
+	if result.ExitCode != min(missing, 255) {
+		return nil, fmt.Errorf("%s: exit status %d: %s", command, result.ExitCode, result.output())
+	}
+
+	return parseTabSeparatedVersions(result.Stdout), nil
+}
+
+func (m *dnfPackageManager) Upgradable(names []string) (map[string]string, error) {
+	result, err := runChecked(context.Background(), m.runner, Command{
+		Name: "dnf",
+		Args: append(
+			[]string{"repoquery", "-q", "--upgrades", "--latest-limit=1", "--qf", "%{name}\t%{version}-%{release}\n", "--"},
+			names...,
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTabSeparatedVersions(result.Stdout), nil
+}
+
+// Install falls back to a downgrade, since dnf refuses to install a version
+// older than the installed one.
+func (m *dnfPackageManager) Install(name, version string) error {
+	if version == "" {
+		return m.dnf("install", name)
+	}
+
+	err := m.dnf("install", name+"-"+version)
+	if err == nil {
+		return nil
+	}
+
+	return errors.Join(err, m.dnf("downgrade", name+"-"+version))
+}
+
+func (m *dnfPackageManager) Upgrade(name string) error {
+	return m.dnf("upgrade", name)
+}
+
+func (m *dnfPackageManager) Remove(name string) error {
+	return m.dnf("remove", name)
+}
+
+func (m *dnfPackageManager) dnf(args ...string) error {
+	_, err := runChecked(context.Background(), m.runner, Command{
+		Name: "dnf",
+		Args: append([]string{"-y", "-q", args[0], "--"}, args[1:]...),
+	})
+
+	return err
+}
+
+func parseTabSeparatedVersions(output []byte) map[string]string {
+	versions := make(map[string]string)
+
+	for _, line := range strings.Split(string(output), "\n") {
+		name, version, ok := strings.Cut(line, "\t")
+		if ok {
+			versions[name] = version
+		}
+	}
+
+	return versions
+}
+
+// apkPackageManager lists and changes the packages with apk.
+type apkPackageManager struct {
+	packageManagerBase
+}
+
+func NewApkPackageManager(opts ...PackageManagerOption) PackageManager {
+	return &apkPackageManager{packageManagerBase: newPackageManagerBase(opts...)}
+}
+
+func (m *apkPackageManager) Installed(names []string) (map[string]string, error) {
+	return m.list(names, "--installed")
+}
+
+func (m *apkPackageManager) Upgradable(names []string) (map[string]string, error) {
+	return m.list(names, "--upgradable")
+}
+
+// list lists all the packages matching the flag, since apk can't list the
+// exact names, and picks the requested ones.
+func (m *apkPackageManager) list(names []string, flag string) (map[string]string, error) {
+	result, err := runChecked(context.Background(), m.runner, Command{Name: "apk", Args: []string{"list", flag}})
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]string)
+	for _, line := range strings.Split(string(result.Stdout), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		name, version, ok := splitApkPackage(fields[0])
+		if ok && slices.Contains(names, name) {
+			versions[name] = version
+		}
+	}
+
+	return versions, nil
+}
+
+func (m *apkPackageManager) Install(name, version string) error {
+	if version != "" {
+		name += "=" + version
+	}
+
+	return m.apk("add", name)
+}
+
+func (m *apkPackageManager) Upgrade(name string) error {
+	return m.apk("add", "-u", name)
+}
+
+func (m *apkPackageManager) Remove(name string) error {
+	return m.apk("del", name)
+}
+
+func (m *apkPackageManager) apk(args ...string) error {
+	_, err := runChecked(context.Background(), m.runner, Command{Name: "apk", Args: append([]string{"-q"}, args...)})
+	return err
+}
+
+// splitApkPackage splits "name-1.2.3-r0" into the name and the version, which
+// are the last two dash-separated parts.
+func splitApkPackage(pkg string) (string, string, bool) {
+	release := strings.LastIndex(pkg, "-")
+	if release == -1 {
+		return "", "", false
+	}
+
+	version := strings.LastIndex(pkg[:release], "-")
+	if version == -1 {
+		return "", "", false
+	}
+
+	return pkg[:version], pkg[version+1:], true
+}
@@ -0,0 +1,243 @@
+package resources
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePackageManager keeps the packages in memory and counts the queries. It
+// batches them like the real managers do.
+type fakePackageManager struct {
+	packageManagerBase
+	mu          sync.Mutex
+	versions    map[string]string
+	newest      map[string]string
+	queries     int
+	queriedWith [][]string
+}
+
+func (m *fakePackageManager) Installed(names []string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.queries++
+	m.queriedWith = append(m.queriedWith, slices.Clone(names))
+
+	installed := make(map[string]string)
+	for _, name := range names {
+		if version, ok := m.versions[name]; ok {
+			installed[name] = version
+		}
+	}
+
+	return installed, nil
+}
+
+func (m *fakePackageManager) Upgradable(names []string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	upgradable := make(map[string]string)
+	for _, name := range names {
+		version, ok := m.versions[name]
+		if newest := m.newest[name]; ok && newest != "" && newest != version {
+			upgradable[name] = newest
+		}
+	}
+
+	return upgradable, nil
+}
+
+func (m *fakePackageManager) Install(name, version string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if version == "" {
+		version = m.newest[name]
+	}
+
+	m.versions[name] = version
+	return nil
+}
+
+func (m *fakePackageManager) Upgrade(name string) error {
+	return m.Install(name, "")
+}
+
+func (m *fakePackageManager) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.versions, name)
+	return nil
+}
+
+func TestPackageCheckUnit(t *testing.T) {
+	t.Run("packages are installed, pinned, upgraded and removed", func(t *testing.T) {
+		manager := &fakePackageManager{
+			packageManagerBase: newPackageManagerBase(),
+			versions:           map[string]string{"nginx": "1.24.0", "telnet": "0.17"},
+			newest:             map[string]string{"nginx": "1.26.0", "curl": "8.5.0", "git": "2.43.0"},
+		}
+
+		newPackage := func(name string, opts ...PackageOption) *Package {
+			pkg, err := NewPackage(name, append(opts, WithPackageManager(manager))...)
+			assert.NoError(t, err)
+			return pkg
+		}
+
+		curl := newPackage("curl")
+		nginx := newPackage("nginx", WithPackageState(PackageLatest))
+		git := newPackage("git", WithPackageVersion("2.40.0"))
+		telnet := newPackage("telnet", WithPackageState(PackageAbsent))
+
+		expected := map[*Package]Correction{curl: curl.install, nginx: nginx.upgrade, git: git.install, telnet: telnet.remove}
+
+		for pkg, correction := range expected {
+			actual, err := pkg.Check()
+			assertCorrections(t, []Correction{correction}, actual)
+			assert.ErrorIs(t, err, ErrUnalignedResource)
+		}
+
+		assert.Equal(t, 1, manager.queries)
+		assert.Equal(t, [][]string{{"curl", "nginx", "git", "telnet"}}, manager.queriedWith)
+
+		for _, correction := range expected {
+			assert.NoError(t, correction())
+		}
+
+		for pkg := range expected {
+			corrections, err := pkg.Check()
+			assert.Nil(t, corrections)
+			assert.NoError(t, err)
+		}
+
+		assert.Equal(t, 2, manager.queries)
+		assert.Equal(t, map[string]string{"nginx": "1.26.0", "curl": "8.5.0", "git": "2.40.0"}, manager.versions)
+	})
+
+}
+
+func TestNewPackageUnit(t *testing.T) {
+	manager := &fakePackageManager{packageManagerBase: newPackageManagerBase(), versions: map[string]string{}}
+
+	type testCase struct {
+		name     string
+		pkg      string
+		opts     []PackageOption
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "invalid package name",
+			pkg:      "-oDebug=1",
+			expected: `invalid package name: "-oDebug=1"`,
+		},
+		{
+			name:     "invalid package version",
+			pkg:      "curl",
+			opts:     []PackageOption{WithPackageVersion("1 2")},
+			expected: `invalid package version of curl: "1 2"`,
+		},
+		{
+			name:     "version can only be pinned when present",
+			pkg:      "curl",
+			opts:     []PackageOption{WithPackageVersion("1.2"), WithPackageState(PackageLatest)},
+			expected: "package version can only be pinned when present: curl",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewPackage(tc.pkg, append(tc.opts, WithPackageManager(manager))...)
+			assert.ErrorContains(t, err, tc.expected)
+		})
+	}
+
+	assert.Empty(t, manager.batch.names)
+}
+
+func TestPackageManagersUnit(t *testing.T) {
+	t.Run("apt", func(t *testing.T) {
+		runner := &fakeRunner{answer: func(command Command) CommandResult {
+			switch command.Name {
+			case "dpkg-query":
+				return CommandResult{
+					Stdout:   []byte("curl\tinstalled\t8.5.0-2ubuntu10\nvim\tconfig-files\t2:9.1.0016-1ubuntu7\n"),
+					Stderr:   []byte("dpkg-query: no packages found matching git\n"),
+					ExitCode: 1,
+				}
+			case "apt-cache":
+				return CommandResult{Stdout: []byte(
+					"curl:\n  Installed: 8.5.0-2ubuntu10\n  Candidate: 8.5.0-2ubuntu10.1\n  Version table:\n" +
+						"git:\n  Installed: (none)\n  Candidate: 1:2.43.0-1ubuntu7\n",
+				)}
+			default:
+				return CommandResult{}
+			}
+		}}
+		manager := NewAptPackageManager(WithPackageManagerRunner(runner))
+
+		installed, err := manager.Installed([]string{"curl", "vim", "git"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"curl": "8.5.0-2ubuntu10"}, installed)
+
+		upgradable, err := manager.Upgradable([]string{"curl", "git"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"curl": "8.5.0-2ubuntu10.1"}, upgradable)
+
+		_, err = manager.Upgradable([]string{"curl"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"dpkg-query -W -f=${Package}\t${db:Status-Status}\t${Version}\n -- curl vim git",
+			"apt-get -y -q update",
+			"apt-cache policy -- curl git",
+			"apt-cache policy -- curl",
+		}, runner.commands)
+
+		assert.NoError(t, manager.Install("git", "1:2.43.0-1ubuntu7"))
+		assert.Equal(t, "apt-get -y -q install --allow-downgrades git=1:2.43.0-1ubuntu7", runner.commands[len(runner.commands)-1])
+	})
+
+	t.Run("dnf", func(t *testing.T) {
+		result := CommandResult{
+			Stdout:   []byte("curl\t8.6.0-1.fc40\npackage git is not installed\n"),
+			Stderr:   []byte("warning: Signature not supported. Hash algorithm SHA1 not available.\n"),
+			ExitCode: 1,
+		}
+		runner := &fakeRunner{answer: func(command Command) CommandResult { return result }}
+		manager := NewDnfPackageManager(WithPackageManagerRunner(runner))
+
+		installed, err := manager.Installed([]string{"curl", "git"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"curl": "8.6.0-1.fc40"}, installed)
+
+		result = CommandResult{Stderr: []byte("error: rpmdb open failed\n"), ExitCode: 1}
+
+		_, err = manager.Installed([]string{"curl", "git"})
+		assert.ErrorContains(t, err, "exit status 1: error: rpmdb open failed")
+
+		result = CommandResult{Stdout: []byte("error: cannot open Packages index\n")}
+
+		_, err = manager.Installed([]string{"curl"})
+		assert.ErrorContains(t, err, "unexpected output: error: cannot open Packages index")
+	})
+
+	t.Run("apk", func(t *testing.T) {
+		runner := &fakeRunner{answer: func(command Command) CommandResult {
+			return CommandResult{Stdout: []byte(
+				"curl-8.5.0-r0 x86_64 {curl} (curl) [installed]\nca-certificates-bundle-20240226-r0 x86_64 {ca-certificates} (MPL-2.0 AND MIT) [installed]\n",
+			)}
+		}}
+		manager := NewApkPackageManager(WithPackageManagerRunner(runner))
+
+		installed, err := manager.Installed([]string{"curl", "ca-certificates-bundle", "git"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"curl": "8.5.0-r0", "ca-certificates-bundle": "20240226-r0"}, installed)
+		assert.Equal(t, []string{"apk list --installed"}, runner.commands)
+	})
+}
@@ -0,0 +1,170 @@
+package resources
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/scherepiuk/align/internal/types"
+)
+
+const (
+	passwdPath  = "/etc/passwd"
+	groupPath   = "/etc/group"
+	shadowPath  = "/etc/shadow"
+	gshadowPath = "/etc/gshadow"
+)
+
+// passwdEntry is a line of /etc/passwd. Unlike os/user, it exposes the shell.
+type passwdEntry struct {
+	name  string
+	uid   int
+	gid   int
+	gecos string
+	home  string
+	shell string
+}
+
+// shadowEntry is a line of /etc/shadow. Dates are in days since the epoch,
+// empty fields are represented by absent optionals.
+type shadowEntry struct {
+	name       string
+	password   string
+	lastChange types.Optional[int]
+	minAge     types.Optional[int]
+	maxAge     types.Optional[int]
+	warnDays   types.Optional[int]
+	inactive   types.Optional[int]
+	expire     types.Optional[int]
+}
+
+func (e shadowEntry) locked() bool {
+	return strings.HasPrefix(e.password, "!")
+}
+
+func (e shadowEntry) expired(today int) bool {
+	return e.expire.Ok() && e.expire.Value() <= today
+}
+
+// groupEntry is a line of /etc/group. Unlike os/user, it exposes the members.
+type groupEntry struct {
+	name    string
+	gid     int
+	members []string
+}
+
+func readEntries[T any](path string, parse func(line string) (T, error)) ([]T, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	entries := make([]T, 0)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry, err := parse(line)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return entries, nil
+}
+
+func parsePasswdEntry(line string) (passwdEntry, error) {
+	fields := strings.Split(line, ":")
+	if len(fields) != 7 {
+		return passwdEntry{}, fmt.Errorf("malformed passwd entry: %q", line)
+	}
+
+	uid, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return passwdEntry{}, fmt.Errorf("failed to parse uid: %w", err)
+	}
+
+	gid, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return passwdEntry{}, fmt.Errorf("failed to parse gid: %w", err)
+	}
+
+	entry := passwdEntry{
+		name:  fields[0],
+		uid:   uid,
+		gid:   gid,
+		gecos: fields[4],
+		home:  fields[5],
+		shell: fields[6],
+	}
+
+	return entry, nil
+}
+
+func parseShadowEntry(line string) (shadowEntry, error) {
+	fields := strings.Split(line, ":")
+	if len(fields) != 9 {
+		return shadowEntry{}, fmt.Errorf("malformed shadow entry: %q", line)
+	}
+
+	numbers := make([]types.Optional[int], 0, 6)
+	for _, field := range fields[2:8] {
+		if field == "" {
+			numbers = append(numbers, types.Optional[int]{})
+			continue
+		}
+
+		number, err := strconv.Atoi(field)
+		if err != nil {
+			return shadowEntry{}, fmt.Errorf("failed to parse shadow field: %w", err)
+		}
+
+		numbers = append(numbers, types.NewOptional(number))
+	}
+
+	entry := shadowEntry{
+		name:       fields[0],
+		password:   fields[1],
+		lastChange: numbers[0],
+		minAge:     numbers[1],
+		maxAge:     numbers[2],
+		warnDays:   numbers[3],
+		inactive:   numbers[4],
+		expire:     numbers[5],
+	}
+
+	return entry, nil
+}
+
+func parseGroupEntry(line string) (groupEntry, error) {
+	fields := strings.Split(line, ":")
+	if len(fields) != 4 {
+		return groupEntry{}, fmt.Errorf("malformed group entry: %q", line)
+	}
+
+	gid, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return groupEntry{}, fmt.Errorf("failed to parse gid: %w", err)
+	}
+
+	members := []string{}
+	if fields[3] != "" {
+		members = strings.Split(fields[3], ",")
+	}
+
+	return groupEntry{name: fields[0], gid: gid, members: members}, nil
+}
@@ -0,0 +1,417 @@
+package resources
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/scherepiuk/align/internal/logger"
+	"github.com/scherepiuk/align/internal/types"
+)
+
+const (
+	defaultUnitDir         = "/etc/systemd/system"
+	defaultServicePolling  = 5 * time.Second
+	defaultServiceTimeout  = 2 * time.Minute
+	unitFileMode           = os.FileMode(0o644)
+	unitDropInDirExtension = ".d"
+)
+
+var (
+	// enabledUnitFileStates make systemctl is-enabled succeed. Only the
+	// enabled ones can be disabled though, the others aren't installed by
+	// the [Install] section.
+	enabledUnitFileStates = []string{"enabled", "enabled-runtime", "static", "alias", "indirect", "generated"}
+	maskedUnitFileStates  = []string{"masked", "masked-runtime"}
+	// activeStates are the ones the unit is (or is about to be) running in,
+	// so it isn't started again.
+	activeStates = []string{"active", "activating", "reloading"}
+)
+
+// Service manages a systemd unit: its unit file and drop-ins, whether it is
+// enabled or masked, and whether it is running. Changing the files reloads
+// systemd, but does not restart the unit.
+type Service struct {
+	BaseDependant
+	name    string
+	dir     string
+	unit    types.Optional[string]
+	dropIns map[string]string
+	enabled types.Optional[bool]
+	masked  types.Optional[bool]
+	active  types.Optional[bool]
+	backup  types.Optional[*Backup]
+
+	runner       CommandRunner
+	timeout      time.Duration
+	pollInterval time.Duration
+}
+
+// NewService manages the unit by its full name (e.g., "nginx.service"). It
+// returns an error if the options contradict each other.
+func NewService(name string, opts ...ServiceOption) (*Service, error) {
+	service := &Service{
+		name:         name,
+		dir:          defaultUnitDir,
+		dropIns:      make(map[string]string),
+		runner:       NewExecRunner(),
+		timeout:      defaultServiceTimeout,
+		pollInterval: defaultServicePolling,
+	}
+
+	for _, opt := range opts {
+		opt(service)
+	}
+
+	// NOTE: Masking replaces the unit file with a symlink to /dev/null, so
+	// the service would keep undoing its own corrections.
+	if service.unit.Ok() && service.masked.Value() {
+		return nil, fmt.Errorf("masked service can't have a unit file: %s", name)
+	}
+
+	return service, nil
+}
+
+type ServiceOption func(service *Service)
+
+func WithUnitFile(content string) ServiceOption {
+	return func(service *Service) {
+		logger.Global().Info("specifying unit file", "name", service.name, "size", len(content))
+		service.unit = types.NewOptional(content)
+	}
+}
+
+// WithDropIn adds a drop-in (e.g., "override" for override.conf), which is
+// how units shipped by packages are changed.
+func WithDropIn(name, content string) ServiceOption {
+	return func(service *Service) {
+		logger.Global().Info("specifying unit drop-in", "name", service.name, "drop-in", name, "size", len(content))
+		service.dropIns[name] = content
+	}
+}
+
+// WithUnitDir changes the directory the unit file and drop-ins are written to.
+func WithUnitDir(dir string) ServiceOption {
+	return func(service *Service) {
+		logger.Global().Info("specifying unit directory", "name", service.name, "dir", dir)
+		service.dir = dir
+	}
+}
+
+func WithEnabled(enabled bool) ServiceOption {
+	return func(service *Service) {
+		logger.Global().Info("specifying unit enablement", "name", service.name, "enabled", enabled)
+		service.enabled = types.NewOptional(enabled)
+	}
+}
+
+func WithMasked(masked bool) ServiceOption {
+	return func(service *Service) {
+		logger.Global().Info("specifying unit mask", "name", service.name, "masked", masked)
+		service.masked = types.NewOptional(masked)
+	}
+}
+
+func WithActive(active bool) ServiceOption {
+	return func(service *Service) {
+		logger.Global().Info("specifying unit activity", "name", service.name, "active", active)
+		service.active = types.NewOptional(active)
+	}
+}
+
+// WithServiceBackup saves the unit file and the drop-ins before they are
+// overwritten.
+func WithServiceBackup(backup *Backup) ServiceOption {
+	return func(service *Service) {
+		logger.Global().Info("specifying service backup", "name", service.name, "dir", backup.dir)
+		service.backup = types.NewOptional(backup)
+	}
+}
+
+// WithServiceRunner changes how systemctl is run, the commands are executed
+// on the host by default.
+func WithServiceRunner(runner CommandRunner) ServiceOption {
+	return func(service *Service) {
+		logger.Global().Info("specifying service runner", "name", service.name, "runner", fmt.Sprintf("%T", runner))
+		service.runner = runner
+	}
+}
+
+// WithServiceTimeout changes how long each systemctl command (e.g., starting
+// the unit) can run, which is 2 minutes by default.
+func WithServiceTimeout(timeout time.Duration) ServiceOption {
+	return func(service *Service) {
+		logger.Global().Info("specifying service timeout", "name", service.name, "timeout", timeout)
+		service.timeout = timeout
+	}
+}
+
+// WithServicePolling changes how often the state of the unit is polled.
+func WithServicePolling(interval time.Duration) ServiceOption {
+	return func(service *Service) {
+		logger.Global().Info("specifying service polling", "name", service.name, "interval", interval)
+		service.pollInterval = interval
+	}
+}
+
+func (s *Service) Id() string {
+	return s.name
+}
+
+func (s *Service) Check() ([]Correction, error) {
+	corrections := make([]Correction, 0)
+
+	drift, err := s.filesDrift()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(drift) > 0 {
+		logger.Global().Warn("unit has wrong files", "name", s.name, "paths", drift)
+		corrections = append(corrections, s.installFiles)
+	}
+
+	state, err := s.state()
+	if err != nil {
+		return nil, err
+	}
+
+	masked := slices.Contains(maskedUnitFileStates, state.unitFileState)
+	if s.masked.Ok() && masked != s.masked.Value() {
+		logger.Global().Warn(
+			"unit has wrong mask", "name", s.name,
+			"masked.actual", masked, "masked.target", s.masked.Value(),
+		)
+		corrections = append(corrections, s.changeMasked)
+	}
+
+	enabled := slices.Contains(enabledUnitFileStates, state.unitFileState)
+	disableable := strings.HasPrefix(state.unitFileState, "enabled")
+	if s.enabled.Ok() && (s.enabled.Value() && !enabled || !s.enabled.Value() && disableable) {
+		logger.Global().Warn(
+			"unit has wrong enablement", "name", s.name,
+			"state.actual", state.unitFileState, "enabled.target", s.enabled.Value(),
+		)
+		corrections = append(corrections, s.changeEnabled)
+	}
+
+	active := slices.Contains(activeStates, state.activeState)
+	if s.active.Ok() && active != s.active.Value() {
+		logger.Global().Warn(
+			"unit has wrong activity", "name", s.name,
+			"state.actual", state.activeState, "active.target", s.active.Value(),
+		)
+		corrections = append(corrections, s.changeActive)
+	}
+
+	if len(corrections) > 0 {
+		return corrections, ErrUnalignedResource
+	}
+
+	return nil, nil
+}
+
+// Watch polls the state of the unit (see WithServicePolling), which covers
+// its files as well.
+func (s *Service) Watch(
+	ctx context.Context,
+	correctionsCh chan<- []Correction,
+	errCh chan<- error,
+) {
+	err := check(s, correctionsCh)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			errCh <- ctx.Err()
+			return
+
+		case <-ticker.C:
+			err := check(s, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}
+}
+
+// files returns the content of the unit file and the drop-ins, keyed by their
+// paths.
+func (s *Service) files() map[string]string {
+	files := make(map[string]string, len(s.dropIns)+1)
+
+	if s.unit.Ok() {
+		files[filepath.Join(s.dir, s.name)] = s.unit.Value()
+	}
+
+	for name, content := range s.dropIns {
+		files[filepath.Join(s.dir, s.name+unitDropInDirExtension, name+".conf")] = content
+	}
+
+	return files
+}
+
+// filesDrift returns the paths of the files with wrong (or no) content.
+func (s *Service) filesDrift() ([]string, error) {
+	drift := make([]string, 0)
+
+	for path, content := range s.files() {
+		actual, err := os.ReadFile(path)
+
+		if errors.Is(err, os.ErrNotExist) {
+			drift = append(drift, path)
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read unit file: %w", err)
+		}
+
+		if string(actual) != content {
+			drift = append(drift, path)
+		}
+	}
+
+	slices.Sort(drift)
+	return drift, nil
+}
+
+// unitState is what systemctl show tells about the unit.
+type unitState struct {
+	unitFileState string
+	activeState   string
+}
+
+func (s *Service) state() (unitState, error) {
+	result, err := s.systemctl("show", "--property=UnitFileState,ActiveState", "--", s.name)
+	if err != nil {
+		return unitState{}, fmt.Errorf("failed to show unit: %w", err)
+	}
+
+	state := unitState{}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(result.Stdout)))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+
+		switch key {
+		case "UnitFileState":
+			state.unitFileState = value
+		case "ActiveState":
+			state.activeState = value
+		}
+	}
+
+	return state, nil
+}
+
+// installFiles writes the unit file and the drop-ins, and reloads systemd so
+// it picks them up.
+func (s *Service) installFiles() error {
+	drift, err := s.filesDrift()
+	if err != nil {
+		return err
+	}
+
+	files := s.files()
+	for _, path := range drift {
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			return fmt.Errorf("failed to create unit directory: %w", err)
+		}
+
+		err = saveBackup(s.backup, path, path)
+		if err != nil {
+			return err
+		}
+
+		err = overwriteFile(path, []byte(files[path]), unitFileMode)
+		if err != nil {
+			return fmt.Errorf("failed to write unit file: %w", err)
+		}
+	}
+
+	_, err = s.systemctl("daemon-reload")
+	if err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) changeMasked() error {
+	if !s.masked.Ok() {
+		return nil
+	}
+
+	verb := "unmask"
+	if s.masked.Value() {
+		verb = "mask"
+	}
+
+	_, err := s.systemctl(verb, "--", s.name)
+	if err != nil {
+		return fmt.Errorf("failed to change unit's mask: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) changeEnabled() error {
+	if !s.enabled.Ok() {
+		return nil
+	}
+
+	verb := "disable"
+	if s.enabled.Value() {
+		verb = "enable"
+	}
+
+	_, err := s.systemctl(verb, "--", s.name)
+	if err != nil {
+		return fmt.Errorf("failed to change unit's enablement: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) changeActive() error {
+	if !s.active.Ok() {
+		return nil
+	}
+
+	verb := "stop"
+	if s.active.Value() {
+		verb = "start"
+	}
+
+	_, err := s.systemctl(verb, "--", s.name)
+	if err != nil {
+		return fmt.Errorf("failed to change unit's activity: %w", err)
+	}
+
+	return nil
+}
+
+// systemctl runs the command under the timeout, so a unit that hangs (e.g.,
+// while starting) doesn't hold up the corrections of the other resources.
+func (s *Service) systemctl(args ...string) (CommandResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	return runChecked(ctx, s.runner, Command{Name: "systemctl", Args: args})
+}
@@ -0,0 +1,134 @@
+package resources
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceCheckUnit(t *testing.T) {
+	showCommand := "systemctl show --property=UnitFileState,ActiveState -- align.service"
+
+	state := func(unitFileState, activeState string) func(command Command) CommandResult {
+		return func(command Command) CommandResult {
+			if command.String() != showCommand {
+				return CommandResult{}
+			}
+
+			return CommandResult{Stdout: []byte("UnitFileState=" + unitFileState + "\nActiveState=" + activeState + "\n")}
+		}
+	}
+
+	t.Run("unit is installed, enabled and started", func(t *testing.T) {
+		dir := t.TempDir()
+		runner := &fakeRunner{answer: state("disabled", "inactive")}
+		service, err := NewService(
+			"align.service",
+			WithUnitDir(dir),
+			WithUnitFile("[Service]\nExecStart=/bin/true\n"),
+			WithDropIn("override", "[Service]\nUser=align\n"),
+			WithEnabled(true),
+			WithMasked(false),
+			WithActive(true),
+			WithServiceRunner(runner),
+		)
+		assert.NoError(t, err)
+		expected := []Correction{service.installFiles, service.changeEnabled, service.changeActive}
+
+		actual, err := service.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		for _, correction := range actual {
+			assert.NoError(t, correction())
+		}
+
+		assert.Equal(t, []string{
+			showCommand,
+			"systemctl daemon-reload",
+			"systemctl enable -- align.service",
+			"systemctl start -- align.service",
+		}, runner.commands)
+
+		content, err := os.ReadFile(filepath.Join(dir, "align.service.d", "override.conf"))
+		assert.NoError(t, err)
+		assert.Equal(t, "[Service]\nUser=align\n", string(content))
+
+		runner.answer = state("enabled", "active")
+
+		corrections, err := service.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("unit is masked and stopped", func(t *testing.T) {
+		runner := &fakeRunner{answer: state("enabled", "activating")}
+		service, err := NewService("align.service", WithMasked(true), WithActive(false), WithServiceRunner(runner))
+		assert.NoError(t, err)
+
+		actual, err := service.Check()
+		assertCorrections(t, []Correction{service.changeMasked, service.changeActive}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		runner.answer = state("masked", "inactive")
+
+		corrections, err := service.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("static unit can't be disabled", func(t *testing.T) {
+		runner := &fakeRunner{answer: state("static", "inactive")}
+
+		for _, enabled := range []bool{true, false} {
+			service, err := NewService("align.service", WithEnabled(enabled), WithServiceRunner(runner))
+			assert.NoError(t, err)
+
+			corrections, err := service.Check()
+			assert.Nil(t, corrections)
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("masked unit with unit file", func(t *testing.T) {
+		_, err := NewService("align.service", WithUnitFile("[Service]\nExecStart=/bin/true\n"), WithMasked(true))
+		assert.ErrorContains(t, err, "masked service can't have a unit file")
+	})
+
+	t.Run("systemctl fails", func(t *testing.T) {
+		runner := &fakeRunner{answer: func(command Command) CommandResult {
+			return CommandResult{Stderr: []byte("Failed to connect to bus"), ExitCode: 1}
+		}}
+		service, err := NewService("align.service", WithActive(true), WithServiceRunner(runner))
+		assert.NoError(t, err)
+
+		corrections, err := service.Check()
+		assert.Nil(t, corrections)
+		assert.ErrorContains(t, err, "Failed to connect to bus")
+	})
+
+	t.Run("systemctl times out", func(t *testing.T) {
+		service, err := NewService(
+			"align.service",
+			WithActive(true),
+			WithServiceRunner(hangingRunner{}),
+			WithServiceTimeout(50*time.Millisecond),
+		)
+		assert.NoError(t, err)
+
+		err = service.changeActive()
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+// hangingRunner runs commands that never finish on their own.
+type hangingRunner struct{}
+
+func (hangingRunner) Run(ctx context.Context, command Command) (CommandResult, error) {
+	<-ctx.Done()
+	return CommandResult{}, ctx.Err()
+}
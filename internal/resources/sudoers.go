@@ -0,0 +1,367 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/scherepiuk/align/internal/logger"
+	"github.com/scherepiuk/align/internal/types"
+	"github.com/scherepiuk/align/internal/utils"
+)
+
+const (
+	defaultSudoersDir = "/etc/sudoers.d"
+	sudoersMode       = os.FileMode(0o440)
+)
+
+var (
+	sudoersNameRegexp  = regexp.MustCompile(`^(#[0-9]+|[A-Za-z_][A-Za-z0-9_.-]*\$?)$`)
+	sudoersHostRegexp  = regexp.MustCompile(`^[A-Za-z0-9_.:/-]+$`)
+	sudoersCommandChar = regexp.MustCompile(`[,:=\\\n]`)
+)
+
+// SudoRule is a single line of a sudoers file. Empty hosts, run-as users and
+// commands mean ALL.
+type SudoRule struct {
+	Users    []string
+	Groups   []string
+	Hosts    []string
+	RunAs    []string
+	Commands []string
+	NoPasswd bool
+}
+
+// Sudoers manages a drop-in file in /etc/sudoers.d. The content is rendered
+// from the rules and validated before it is installed, since a broken sudoers
+// file would lock everyone out of sudo. The file is always 0440 root:root.
+type Sudoers struct {
+	BaseDependant
+	name   string
+	dir    string
+	rules  []SudoRule
+	backup types.Optional[*Backup]
+}
+
+func NewSudoers(name string, opts ...SudoersOption) *Sudoers {
+	sudoers := &Sudoers{name: name, dir: defaultSudoersDir}
+
+	for _, opt := range opts {
+		opt(sudoers)
+	}
+
+	return sudoers
+}
+
+type SudoersOption func(sudoers *Sudoers)
+
+func WithSudoRule(rule SudoRule) SudoersOption {
+	return func(sudoers *Sudoers) {
+		logger.Global().Info("specifying sudo rule", "name", sudoers.name, "rule", rule)
+		sudoers.rules = append(sudoers.rules, rule)
+	}
+}
+
+// WithSudoersDir changes the directory the file is installed to.
+func WithSudoersDir(dir string) SudoersOption {
+	return func(sudoers *Sudoers) {
+		logger.Global().Info("specifying sudoers directory", "name", sudoers.name, "dir", dir)
+		sudoers.dir = dir
+	}
+}
+
+// WithSudoersBackup saves the file before it is replaced.
+func WithSudoersBackup(backup *Backup) SudoersOption {
+	return func(sudoers *Sudoers) {
+		logger.Global().Info("specifying sudoers backup", "name", sudoers.name, "dir", backup.dir)
+		sudoers.backup = types.NewOptional(backup)
+	}
+}
+
+func (s *Sudoers) Id() string {
+	return s.path()
+}
+
+func (s *Sudoers) Check() ([]Correction, error) {
+	// NOTE: sudo silently skips the files with dots in their names or
+	// ending with a tilde, so such a file would never take effect.
+	if strings.Contains(s.name, ".") || strings.HasSuffix(s.name, "~") {
+		return nil, fmt.Errorf("sudoers file would be ignored by sudo: %s", s.name)
+	}
+
+	content, err := s.render()
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(s.path())
+
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Global().Warn("sudoers file does not exist", "path", s.path())
+		return []Correction{s.install}, ErrUnalignedResource
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat sudoers file: %w", err)
+	}
+
+	actual, err := os.ReadFile(s.path())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sudoers file: %w", err)
+	}
+
+	if string(actual) != content {
+		logger.Global().Warn("sudoers file has wrong content", "path", s.path())
+		return []Correction{s.install}, ErrUnalignedResource
+	}
+
+	uid, gid := fileOwnership(stat)
+	if permissionBits(stat.Mode()) != sudoersMode || uid != 0 || gid != 0 {
+		logger.Global().Warn(
+			"sudoers file has wrong mode or ownership", "path", s.path(),
+			"mode", permissionBits(stat.Mode()), "uid", uid, "gid", gid,
+		)
+		return []Correction{s.changePermissions}, ErrUnalignedResource
+	}
+
+	return nil, nil
+}
+
+// Watch observes the directory instead of the file, which is replaced (and
+// not written in place) on every install.
+func (s *Sudoers) Watch(
+	ctx context.Context,
+	correctionsCh chan<- []Correction,
+	errCh chan<- error,
+) {
+	err := check(s, correctionsCh)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		errCh <- err
+		return
+	}
+	defer watcher.Close()
+
+	err = utils.Retry(
+		ctx,
+		func() error { return watcher.Add(s.dir) },
+		100*time.Millisecond,
+		os.ErrNotExist,
+	)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			errCh <- ctx.Err()
+			return
+
+		case err := <-watcher.Errors:
+			errCh <- err
+			return
+
+		case event := <-watcher.Events:
+			if event.Name != s.path() {
+				continue
+			}
+
+			logger.Global().Debug("got fsnotify event", "event", event.String())
+
+			err := check(s, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}
+}
+
+func (s *Sudoers) path() string {
+	return filepath.Join(s.dir, s.name)
+}
+
+// install validates the content in a temporary file next to the target one
+// (which sudo ignores thanks to the dot in its name), and only then renames it
+// over the target.
+func (s *Sudoers) install() error {
+	content, err := s.render()
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(s.dir, fmt.Sprintf(".%s.align", s.name))
+
+	err = os.WriteFile(tmp, []byte(content), sudoersMode)
+	if err != nil {
+		return fmt.Errorf("failed to write temporary sudoers file: %w", err)
+	}
+	defer os.Remove(tmp)
+
+	err = validateSudoersFile(tmp)
+	if err != nil {
+		return err
+	}
+
+	err = setSudoersPermissions(tmp)
+	if err != nil {
+		return err
+	}
+
+	err = saveBackup(s.backup, s.Id(), s.path())
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, s.path())
+	if err != nil {
+		return fmt.Errorf("failed to install sudoers file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Sudoers) changePermissions() error {
+	return setSudoersPermissions(s.path())
+}
+
+func setSudoersPermissions(path string) error {
+	err := os.Chown(path, 0, 0)
+	if err != nil {
+		return fmt.Errorf("failed to change sudoers file's ownership: %w", err)
+	}
+
+	err = os.Chmod(path, sudoersMode)
+	if err != nil {
+		return fmt.Errorf("failed to change sudoers file's mode: %w", err)
+	}
+
+	return nil
+}
+
+// validateSudoersFile runs visudo on the file if it is available. The rules
+// are validated when rendered regardless, visudo is the second opinion.
+func validateSudoersFile(path string) error {
+	visudo, err := exec.LookPath("visudo")
+	if errors.Is(err, exec.ErrNotFound) {
+		logger.Global().Debug("visudo is not available, relying on built-in validation", "path", path)
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to look up visudo: %w", err)
+	}
+
+	output, err := exec.Command(visudo, "-c", "-q", "-f", path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("invalid sudoers content: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// render returns the content of the file, or an error if any of the rules is
+// invalid.
+func (s *Sudoers) render() (string, error) {
+	var builder strings.Builder
+	builder.WriteString("# Managed by align, do not edit.\n")
+
+	for _, rule := range s.rules {
+		line, err := rule.render()
+		if err != nil {
+			return "", fmt.Errorf("invalid sudo rule: %w", err)
+		}
+
+		builder.WriteString(line + "\n")
+	}
+
+	return builder.String(), nil
+}
+
+func (r SudoRule) render() (string, error) {
+	who := make([]string, 0, len(r.Users)+len(r.Groups))
+
+	for _, user := range r.Users {
+		if !sudoersNameRegexp.MatchString(user) {
+			return "", fmt.Errorf("invalid user: %q", user)
+		}
+		who = append(who, user)
+	}
+
+	for _, group := range r.Groups {
+		if !sudoersNameRegexp.MatchString(group) {
+			return "", fmt.Errorf("invalid group: %q", group)
+		}
+		who = append(who, "%"+group)
+	}
+
+	if len(who) == 0 {
+		return "", errors.New("rule has neither users nor groups")
+	}
+
+	hosts := []string{"ALL"}
+	if len(r.Hosts) > 0 {
+		hosts = r.Hosts
+	}
+
+	for _, host := range hosts {
+		if !sudoersHostRegexp.MatchString(host) {
+			return "", fmt.Errorf("invalid host: %q", host)
+		}
+	}
+
+	runAs := []string{"ALL"}
+	if len(r.RunAs) > 0 {
+		runAs = r.RunAs
+	}
+
+	for _, user := range runAs {
+		if user != "ALL" && !sudoersNameRegexp.MatchString(user) {
+			return "", fmt.Errorf("invalid run-as user: %q", user)
+		}
+	}
+
+	commands := []string{"ALL"}
+	if len(r.Commands) > 0 {
+		commands = r.Commands
+	}
+
+	for _, command := range commands {
+		valid := command == "ALL" ||
+			strings.HasPrefix(command, "/") ||
+			strings.HasPrefix(command, "sudoedit /")
+		if !valid || sudoersCommandChar.MatchString(command) {
+			return "", fmt.Errorf("invalid command: %q", command)
+		}
+	}
+
+	tag := ""
+	if r.NoPasswd {
+		tag = "NOPASSWD: "
+	}
+
+	line := fmt.Sprintf(
+		"%s %s=(%s) %s%s",
+		strings.Join(who, ", "),
+		strings.Join(hosts, ", "),
+		strings.Join(runAs, ", "),
+		tag,
+		strings.Join(commands, ", "),
+	)
+
+	return line, nil
+}
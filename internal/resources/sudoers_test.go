@@ -0,0 +1,138 @@
+package resources
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSudoRuleRenderUnit(t *testing.T) {
+	type testCase struct {
+		name     string
+		rule     SudoRule
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "rule with defaults",
+			rule:     SudoRule{Groups: []string{"wheel"}},
+			expected: "%wheel ALL=(ALL) ALL",
+		},
+		{
+			name: "rule with everything",
+			rule: SudoRule{
+				Users:    []string{"deploy", "#1001"},
+				Groups:   []string{"ops"},
+				Hosts:    []string{"web1", "10.0.0.0/8"},
+				RunAs:    []string{"root"},
+				Commands: []string{"/usr/bin/systemctl restart app", "sudoedit /etc/app.conf"},
+				NoPasswd: true,
+			},
+			expected: "deploy, #1001, %ops web1, 10.0.0.0/8=(root) " +
+				"NOPASSWD: /usr/bin/systemctl restart app, sudoedit /etc/app.conf",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := tc.rule.render()
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	invalidRules := map[string]SudoRule{
+		"rule without users":       {Commands: []string{"ALL"}},
+		"user with a space":        {Users: []string{"bad user"}},
+		"relative command":         {Users: []string{"deploy"}, Commands: []string{"systemctl"}},
+		"command with a separator": {Users: []string{"deploy"}, Commands: []string{"/bin/true, ALL"}},
+		"command with a newline":   {Users: []string{"deploy"}, Commands: []string{"/bin/true\nALL ALL=(ALL) ALL"}},
+		"host with a separator":    {Users: []string{"deploy"}, Hosts: []string{"web1=ALL"}},
+	}
+
+	for name, rule := range invalidRules {
+		t.Run(name, func(t *testing.T) {
+			_, err := rule.render()
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSudoersCheckIntegration(t *testing.T) {
+	t.Run("sudoers file is installed and fixed", func(t *testing.T) {
+		dir := t.TempDir()
+		sudoers := NewSudoers(
+			"align",
+			WithSudoersDir(dir),
+			WithSudoRule(SudoRule{Users: []string{"deploy"}, Commands: []string{"/bin/true"}, NoPasswd: true}),
+		)
+
+		actual, err := sudoers.Check()
+		assertCorrections(t, []Correction{sudoers.install}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		err = sudoers.install()
+		assert.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(dir, "align"))
+		assert.NoError(t, err)
+		assert.Equal(t, "# Managed by align, do not edit.\ndeploy ALL=(ALL) NOPASSWD: /bin/true\n", string(content))
+
+		corrections, err := sudoers.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		err = os.Chmod(filepath.Join(dir, "align"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err = sudoers.Check()
+		assertCorrections(t, []Correction{sudoers.changePermissions}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		err = sudoers.changePermissions()
+		assert.NoError(t, err)
+
+		corrections, err = sudoers.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("invalid rule is never installed", func(t *testing.T) {
+		dir := t.TempDir()
+		sudoers := NewSudoers(
+			"align",
+			WithSudoersDir(dir),
+			WithSudoRule(SudoRule{Users: []string{"deploy"}, Commands: []string{"rm -rf /"}}),
+		)
+
+		corrections, err := sudoers.Check()
+		assert.Nil(t, corrections)
+		assert.ErrorContains(t, err, "invalid sudo rule")
+
+		err = sudoers.install()
+		assert.ErrorContains(t, err, "invalid sudo rule")
+
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("sudoers file would be ignored", func(t *testing.T) {
+		sudoers := NewSudoers("align.conf", WithSudoersDir(t.TempDir()))
+
+		corrections, err := sudoers.Check()
+		assert.Nil(t, corrections)
+		assert.ErrorContains(t, err, "would be ignored by sudo")
+	})
+}
@@ -0,0 +1,330 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/scherepiuk/align/internal/logger"
+	"github.com/scherepiuk/align/internal/types"
+	"github.com/scherepiuk/align/internal/utils"
+)
+
+// Symlink manages a symbolic link. A dangling link is reported, but left
+// alone, as the target is expected to be managed by one of the dependencies.
+type Symlink struct {
+	BaseDependant
+	path   string
+	target string
+	owner  types.Optional[string]
+	group  types.Optional[string]
+	backup types.Optional[*Backup]
+}
+
+func NewSymlink(path, target string, opts ...SymlinkOption) *Symlink {
+	symlink := &Symlink{path: filepath.Clean(path), target: target}
+
+	for _, opt := range opts {
+		opt(symlink)
+	}
+
+	return symlink
+}
+
+type SymlinkOption func(symlink *Symlink)
+
+func WithSymlinkOwner(owner string) SymlinkOption {
+	return func(symlink *Symlink) {
+		logger.Global().Info("specifying symlink owner", "path", symlink.path, "owner", owner)
+		symlink.owner = types.NewOptional(owner)
+	}
+}
+
+func WithSymlinkGroup(group string) SymlinkOption {
+	return func(symlink *Symlink) {
+		logger.Global().Info("specifying symlink group", "path", symlink.path, "group", group)
+		symlink.group = types.NewOptional(group)
+	}
+}
+
+// WithSymlinkBackup saves whatever is at the path (the symlink with the wrong
+// target, or the regular file or the directory that replaced it) before it's
+// replaced.
+func WithSymlinkBackup(backup *Backup) SymlinkOption {
+	return func(symlink *Symlink) {
+		logger.Global().Info("specifying symlink backup", "path", symlink.path, "dir", backup.dir)
+		symlink.backup = types.NewOptional(backup)
+	}
+}
+
+func (s *Symlink) Id() string {
+	return s.path
+}
+
+func (s *Symlink) Check() ([]Correction, error) {
+	stat, err := os.Lstat(s.path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Global().Warn("symlink does not exist", "path", s.path)
+		corrections := []Correction{
+			s.create,
+			s.changeOwner,
+			s.changeGroup,
+		}
+		return corrections, ErrUnalignedResource
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat symlink: %w", err)
+	}
+
+	switch {
+	case stat.Mode().IsRegular():
+		logger.Global().Warn("symlink has been replaced by a regular file", "path", s.path)
+		corrections := []Correction{
+			s.replaceFile,
+			s.changeOwner,
+			s.changeGroup,
+		}
+		return corrections, ErrUnalignedResource
+
+	case stat.IsDir():
+		logger.Global().Warn("symlink has been replaced by a directory", "path", s.path)
+		corrections := []Correction{
+			s.replaceDirectory,
+			s.changeOwner,
+			s.changeGroup,
+		}
+		return corrections, ErrUnalignedResource
+
+	case stat.Mode()&os.ModeSymlink == 0:
+		return nil, fmt.Errorf("path is not a symlink: %s", s.path)
+	}
+
+	corrections := make([]Correction, 0)
+
+	target, err := os.Readlink(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read symlink: %w", err)
+	}
+
+	if target != s.target {
+		logger.Global().Warn(
+			"symlink has wrong target", "path", s.path,
+			"target.actual", target, "target.target", s.target,
+		)
+		corrections = append(corrections, s.retarget)
+	} else if _, err := os.Stat(s.path); errors.Is(err, os.ErrNotExist) {
+		// NOTE: There is nothing the symlink itself can do about a missing
+		// target, it is expected to be managed by one of the dependencies.
+		logger.Global().Warn("symlink is dangling", "path", s.path, "target", s.target)
+	}
+
+	uid, gid := fileOwnership(stat)
+
+	owner, _ := lookupUid(uid)
+	if s.owner.Ok() && owner != s.owner.Value() {
+		logger.Global().Warn(
+			"symlink has wrong owner", "path", s.path,
+			"owner.actual", owner, "owner.target", s.owner.Value(),
+		)
+		corrections = append(corrections, s.changeOwner)
+	}
+
+	group, _ := lookupGid(gid)
+	if s.group.Ok() && group != s.group.Value() {
+		logger.Global().Warn(
+			"symlink has wrong group", "path", s.path,
+			"group.actual", group, "group.target", s.group.Value(),
+		)
+		corrections = append(corrections, s.changeGroup)
+	}
+
+	if len(corrections) > 0 {
+		return corrections, ErrUnalignedResource
+	}
+
+	return nil, nil
+}
+
+// Watch observes the parent directories of the symlink and of its target
+// instead of the symlink itself. Adding a watch follows symlinks, so watching
+// the path directly would report changes to the target and miss the link
+// being removed, retargeted or replaced.
+func (s *Symlink) Watch(
+	ctx context.Context,
+	correctionsCh chan<- []Correction,
+	errCh chan<- error,
+) {
+	err := check(s, correctionsCh)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		errCh <- err
+		return
+	}
+	defer watcher.Close()
+
+	targetPath := s.target
+	if !filepath.IsAbs(targetPath) {
+		targetPath = filepath.Join(filepath.Dir(s.path), targetPath)
+	}
+	targetPath = filepath.Clean(targetPath)
+
+	for _, dir := range []string{filepath.Dir(s.path), filepath.Dir(targetPath)} {
+		err = utils.Retry(
+			ctx,
+			func() error { return watcher.Add(dir) },
+			100*time.Millisecond,
+			os.ErrNotExist,
+		)
+		if err != nil {
+			errCh <- err
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			errCh <- ctx.Err()
+			return
+
+		case err := <-watcher.Errors:
+			errCh <- err
+			return
+
+		case event := <-watcher.Events:
+			if event.Name != s.path && event.Name != targetPath {
+				continue
+			}
+
+			logger.Global().Debug("got fsnotify event", "event", event.String())
+
+			err := check(s, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}
+}
+
+func (s *Symlink) create() error {
+	err := os.Symlink(s.target, s.path)
+	if err != nil {
+		return fmt.Errorf("failed to create symlink: %w", err)
+	}
+
+	return nil
+}
+
+// retarget atomically replaces whatever is at the path (except for
+// a directory) with the symlink by renaming a temporary symlink over it.
+func (s *Symlink) retarget() error {
+	err := s.saveBackup()
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(filepath.Dir(s.path), fmt.Sprintf(".%s.align", filepath.Base(s.path)))
+
+	err = os.Remove(tmp)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove temporary symlink: %w", err)
+	}
+
+	err = os.Symlink(s.target, tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary symlink: %w", err)
+	}
+
+	err = os.Rename(tmp, s.path)
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace symlink: %w", err)
+	}
+
+	return nil
+}
+
+// replaceFile is backed up by retarget.
+func (s *Symlink) replaceFile() error {
+	err := s.retarget()
+	if err != nil {
+		return fmt.Errorf("failed to replace file with symlink: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Symlink) replaceDirectory() error {
+	err := s.saveBackup()
+	if err != nil {
+		return err
+	}
+
+	err = os.RemoveAll(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to remove directory: %w", err)
+	}
+
+	return s.create()
+}
+
+func (s *Symlink) saveBackup() error {
+	if !s.backup.Ok() {
+		return nil
+	}
+
+	err := s.backup.Value().Save(s.Id(), s.path)
+	if err != nil {
+		return fmt.Errorf("failed to back up symlink: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Symlink) changeOwner() error {
+	if !s.owner.Ok() {
+		return nil
+	}
+
+	uid, err := lookupUser(s.owner.Value())
+	if err != nil {
+		return fmt.Errorf("failed to lookup user: %w", err)
+	}
+
+	err = os.Lchown(s.path, uid, -1)
+	if err != nil {
+		return fmt.Errorf("failed to change symlink's owner: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Symlink) changeGroup() error {
+	if !s.group.Ok() {
+		return nil
+	}
+
+	gid, err := lookupGroup(s.group.Value())
+	if err != nil {
+		return fmt.Errorf("failed to lookup group: %w", err)
+	}
+
+	err = os.Lchown(s.path, -1, gid)
+	if err != nil {
+		return fmt.Errorf("failed to change symlink's group: %w", err)
+	}
+
+	return nil
+}
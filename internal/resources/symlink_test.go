@@ -0,0 +1,145 @@
+package resources
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSymlinkCheckIntegration(t *testing.T) {
+	t.Run("symlink does not exist", func(t *testing.T) {
+		path, target := testFilePath(), testFilePath()
+
+		symlink := NewSymlink(path, target)
+		expected := []Correction{
+			symlink.create,
+			symlink.changeOwner,
+			symlink.changeGroup,
+		}
+
+		actual, err := symlink.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+	})
+
+	t.Run("symlink has wrong target", func(t *testing.T) {
+		path, target := testFilePath(), testFilePath()
+		testSymlink(t, path, "/tmp")
+
+		symlink := NewSymlink(path, target)
+		expected := []Correction{symlink.retarget}
+
+		actual, err := symlink.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		err = symlink.retarget()
+		assert.NoError(t, err)
+
+		actualTarget, err := os.Readlink(path)
+		assert.NoError(t, err)
+		assert.Equal(t, target, actualTarget)
+	})
+
+	t.Run("symlink has been replaced by a regular file", func(t *testing.T) {
+		path, target := testFilePath(), testFilePath()
+
+		err := os.WriteFile(path, nil, 0o664)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(path) })
+
+		symlink := NewSymlink(path, target)
+		expected := []Correction{
+			symlink.replaceFile,
+			symlink.changeOwner,
+			symlink.changeGroup,
+		}
+
+		actual, err := symlink.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+	})
+
+	t.Run("symlink has been replaced by a directory", func(t *testing.T) {
+		path, target := testDirectory(t), testFilePath()
+
+		symlink := NewSymlink(path, target)
+		expected := []Correction{
+			symlink.replaceDirectory,
+			symlink.changeOwner,
+			symlink.changeGroup,
+		}
+
+		actual, err := symlink.Check()
+		assertCorrections(t, expected, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+	})
+
+	t.Run("symlink is dangling", func(t *testing.T) {
+		path, target := testFilePath(), testFilePath()
+		testSymlink(t, path, target)
+
+		symlink := NewSymlink(path, target)
+
+		corrections, err := symlink.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("symlink is aligned", func(t *testing.T) {
+		path := testFilePath()
+		testSymlink(t, path, "/tmp")
+
+		symlink := NewSymlink(path, "/tmp")
+
+		corrections, err := symlink.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+}
+
+func TestSymlinkWatchIntegration(t *testing.T) {
+	t.Run("symlink has been retargeted", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		t.Cleanup(cancel)
+
+		path := testFilePath()
+		correctionsCh, errCh := make(chan []Correction), make(chan error)
+		testSymlink(t, path, "/tmp")
+
+		symlink := NewSymlink(path, "/tmp")
+		expected := []Correction{symlink.retarget}
+
+		go symlink.Watch(ctx, correctionsCh, errCh)
+		time.Sleep(time.Second)
+
+		err := os.Remove(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.Symlink("/var", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// NOTE: Removal of the symlink is reported first.
+		<-correctionsCh
+
+		actual := <-correctionsCh
+		assertCorrections(t, expected, actual)
+	})
+}
+
+func testSymlink(t *testing.T, path, target string) {
+	err := os.Symlink(target, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(path) })
+}
@@ -0,0 +1,372 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/scherepiuk/align/internal/logger"
+	"github.com/scherepiuk/align/internal/types"
+	"github.com/scherepiuk/align/internal/utils"
+)
+
+const (
+	defaultProcRoot       = "/proc/sys"
+	defaultSysctlDir      = "/etc/sysctl.d"
+	defaultSysctlFile     = "99-align.conf"
+	defaultSysctlPolling  = 5 * time.Second
+	sysctlFileMode        = os.FileMode(0o644)
+	sysctlIgnoreErrPrefix = "-"
+)
+
+// Sysctl manages a kernel parameter, both its runtime value in /proc/sys and
+// the value persisted in a file in /etc/sysctl.d, which applies it on boot.
+// Only the line of the parameter is managed, so the file can be shared.
+type Sysctl struct {
+	BaseDependant
+	key   string
+	value string
+
+	procRoot string
+	dir      string
+	file     string
+	backup   types.Optional[*Backup]
+
+	pollInterval time.Duration
+}
+
+// NewSysctl manages the parameter by its key (e.g., "net.ipv4.ip_forward"). It
+// returns an error if the key or the value is invalid, so a broken line is
+// never persisted.
+func NewSysctl(key, value string, opts ...SysctlOption) (*Sysctl, error) {
+	sysctl := &Sysctl{
+		key:          key,
+		value:        value,
+		procRoot:     defaultProcRoot,
+		dir:          defaultSysctlDir,
+		file:         defaultSysctlFile,
+		pollInterval: defaultSysctlPolling,
+	}
+
+	for _, opt := range opts {
+		opt(sysctl)
+	}
+
+	// NOTE: Every name has to be a plain one, so the key can't escape
+	// /proc/sys (e.g., "net/../../etc/passwd").
+	names := strings.Split(normalizeSysctlKey(key), "/")
+	invalidName := slices.ContainsFunc(names, func(name string) bool {
+		return name == "" || name == "." || name == ".."
+	})
+
+	if invalidName || strings.ContainsAny(key, " \t=\n") {
+		return nil, fmt.Errorf("invalid sysctl key: %q", key)
+	}
+
+	if strings.Contains(value, "\n") {
+		return nil, fmt.Errorf("invalid sysctl value of %s: %q", key, value)
+	}
+
+	return sysctl, nil
+}
+
+type SysctlOption func(sysctl *Sysctl)
+
+// WithProcRoot changes the directory the runtime values are read from and
+// written to.
+func WithProcRoot(root string) SysctlOption {
+	return func(sysctl *Sysctl) {
+		logger.Global().Info("specifying sysctl proc root", "key", sysctl.key, "root", root)
+		sysctl.procRoot = root
+	}
+}
+
+// WithSysctlDir changes the directory the value is persisted in.
+func WithSysctlDir(dir string) SysctlOption {
+	return func(sysctl *Sysctl) {
+		logger.Global().Info("specifying sysctl directory", "key", sysctl.key, "dir", dir)
+		sysctl.dir = dir
+	}
+}
+
+// WithSysctlFile changes the file the value is persisted in. The files are
+// applied in the lexical order, so the later ones win.
+func WithSysctlFile(file string) SysctlOption {
+	return func(sysctl *Sysctl) {
+		logger.Global().Info("specifying sysctl file", "key", sysctl.key, "file", file)
+		sysctl.file = file
+	}
+}
+
+// WithSysctlBackup saves the file before the persisted value is changed.
+func WithSysctlBackup(backup *Backup) SysctlOption {
+	return func(sysctl *Sysctl) {
+		logger.Global().Info("specifying sysctl backup", "key", sysctl.key, "dir", backup.dir)
+		sysctl.backup = types.NewOptional(backup)
+	}
+}
+
+// WithSysctlPolling changes how often the runtime value is polled, since
+// /proc/sys can't be watched.
+func WithSysctlPolling(interval time.Duration) SysctlOption {
+	return func(sysctl *Sysctl) {
+		logger.Global().Info("specifying sysctl polling", "key", sysctl.key, "interval", interval)
+		sysctl.pollInterval = interval
+	}
+}
+
+func (s *Sysctl) Id() string {
+	return "sysctl:" + s.key
+}
+
+func (s *Sysctl) Check() ([]Correction, error) {
+	corrections := make([]Correction, 0)
+
+	runtime, err := s.runtimeValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if runtime != normalizeSysctlValue(s.value) {
+		logger.Global().Warn(
+			"sysctl has wrong runtime value", "key", s.key,
+			"value.actual", runtime, "value.target", s.value,
+		)
+		corrections = append(corrections, s.applyRuntime)
+	}
+
+	persisted, ok, err := s.persistedValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok || persisted != normalizeSysctlValue(s.value) {
+		logger.Global().Warn(
+			"sysctl has wrong persisted value", "key", s.key, "path", s.path(),
+			"value.actual", persisted, "value.target", s.value,
+		)
+		corrections = append(corrections, s.persist)
+	}
+
+	if len(corrections) > 0 {
+		return corrections, ErrUnalignedResource
+	}
+
+	return nil, nil
+}
+
+// Watch polls the runtime value (see WithSysctlPolling), and observes the
+// directory of the file for the persisted one.
+func (s *Sysctl) Watch(
+	ctx context.Context,
+	correctionsCh chan<- []Correction,
+	errCh chan<- error,
+) {
+	err := check(s, correctionsCh)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		errCh <- err
+		return
+	}
+	defer watcher.Close()
+
+	err = utils.Retry(
+		ctx,
+		func() error { return watcher.Add(s.dir) },
+		100*time.Millisecond,
+		os.ErrNotExist,
+	)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			errCh <- ctx.Err()
+			return
+
+		case err := <-watcher.Errors:
+			errCh <- err
+			return
+
+		case event := <-watcher.Events:
+			if event.Name != s.path() {
+				continue
+			}
+
+			logger.Global().Debug("got fsnotify event", "event", event.String())
+
+			err := check(s, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+		case <-ticker.C:
+			err := check(s, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}
+}
+
+func (s *Sysctl) path() string {
+	return filepath.Join(s.dir, s.file)
+}
+
+// procPath returns the path of the parameter in /proc/sys.
+func (s *Sysctl) procPath() string {
+	return filepath.Join(s.procRoot, normalizeSysctlKey(s.key))
+}
+
+func (s *Sysctl) runtimeValue() (string, error) {
+	content, err := os.ReadFile(s.procPath())
+
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("unknown sysctl key: %s", s.key)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read sysctl runtime value: %w", err)
+	}
+
+	return normalizeSysctlValue(string(content)), nil
+}
+
+// persistedValue returns the value set by the last line of the parameter in
+// the file, and whether there is one at all.
+func (s *Sysctl) persistedValue() (string, bool, error) {
+	lines, err := readLines(s.path())
+
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read sysctl file: %w", err)
+	}
+
+	value, ok := "", false
+	for _, line := range lines {
+		key, lineValue, isEntry := parseSysctlLine(line)
+		if isEntry && normalizeSysctlKey(key) == normalizeSysctlKey(s.key) {
+			value, ok = normalizeSysctlValue(lineValue), true
+		}
+	}
+
+	return value, ok, nil
+}
+
+func (s *Sysctl) applyRuntime() error {
+	err := overwriteFile(s.procPath(), []byte(s.value+"\n"), sysctlFileMode)
+	if err != nil {
+		return fmt.Errorf("failed to apply sysctl runtime value: %w", err)
+	}
+
+	return nil
+}
+
+// persist replaces the lines of the parameter in the file with a single one,
+// or appends it if there are none.
+func (s *Sysctl) persist() error {
+	lines, err := readLines(s.path())
+	if errors.Is(err, os.ErrNotExist) {
+		lines = []string{}
+	} else if err != nil {
+		return fmt.Errorf("failed to read sysctl file: %w", err)
+	}
+
+	entry := fmt.Sprintf("%s = %s", s.key, s.value)
+
+	persisted := make([]string, 0, len(lines)+1)
+	replaced := false
+
+	for _, line := range lines {
+		key, _, isEntry := parseSysctlLine(line)
+		if !isEntry || normalizeSysctlKey(key) != normalizeSysctlKey(s.key) {
+			persisted = append(persisted, line)
+		} else if !replaced {
+			persisted = append(persisted, entry)
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		persisted = append(persisted, entry)
+	}
+
+	err = saveBackup(s.backup, s.Id(), s.path())
+	if err != nil {
+		return err
+	}
+
+	err = overwriteFile(s.path(), []byte(strings.Join(persisted, "\n")+"\n"), sysctlFileMode)
+	if err != nil {
+		return fmt.Errorf("failed to persist sysctl value: %w", err)
+	}
+
+	return nil
+}
+
+// parseSysctlLine parses a "key = value" line of sysctl.d(5), the key of a
+// line whose errors are ignored (prefixed with a dash) is returned without
+// the dash.
+func parseSysctlLine(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+		return "", "", false
+	}
+
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", "", false
+	}
+
+	key = strings.TrimPrefix(strings.TrimSpace(key), sysctlIgnoreErrPrefix)
+	return key, strings.TrimSpace(value), true
+}
+
+// normalizeSysctlKey returns the key separated by slashes, as in /proc/sys.
+// The keys are separated either by dots or by slashes, whichever comes first,
+// the other one is a part of the names then (e.g., "net.ipv4.conf.eth0/100"
+// and "net/ipv4/conf/eth0.100" are the same key), see sysctl.d(5).
+func normalizeSysctlKey(key string) string {
+	if i := strings.IndexAny(key, "./"); i == -1 || key[i] == '/' {
+		return key
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.':
+			return '/'
+		case '/':
+			return '.'
+		default:
+			return r
+		}
+	}, key)
+}
+
+// normalizeSysctlValue collapses the whitespace, /proc/sys separates the
+// values of some parameters (e.g., net.ipv4.ip_local_port_range) with tabs.
+func normalizeSysctlValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -0,0 +1,138 @@
+package resources
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSysctlLineUnit(t *testing.T) {
+	type testCase struct {
+		line          string
+		expectedKey   string
+		expectedValue string
+		expectedEntry bool
+	}
+
+	testCases := []testCase{
+		{line: "net.ipv4.ip_forward = 1", expectedKey: "net.ipv4.ip_forward", expectedValue: "1", expectedEntry: true},
+		{line: "  -vm.swappiness=10 ", expectedKey: "vm.swappiness", expectedValue: "10", expectedEntry: true},
+		{line: "# vm.swappiness = 10"},
+		{line: "; vm.swappiness = 10"},
+		{line: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.line, func(t *testing.T) {
+			key, value, entry := parseSysctlLine(tc.line)
+
+			assert.Equal(t, tc.expectedKey, key)
+			assert.Equal(t, tc.expectedValue, value)
+			assert.Equal(t, tc.expectedEntry, entry)
+		})
+	}
+}
+
+func TestNormalizeSysctlKeyUnit(t *testing.T) {
+	assert.Equal(t, "net/ipv4/ip_forward", normalizeSysctlKey("net.ipv4.ip_forward"))
+	assert.Equal(t, "net/ipv4/ip_forward", normalizeSysctlKey("net/ipv4/ip_forward"))
+	assert.Equal(t, "net/ipv4/conf/eth0.100/forwarding", normalizeSysctlKey("net.ipv4.conf.eth0/100.forwarding"))
+	assert.Equal(t, "net/ipv4/conf/eth0.100/forwarding", normalizeSysctlKey("net/ipv4/conf/eth0.100/forwarding"))
+	assert.Equal(t, "kernel", normalizeSysctlKey("kernel"))
+}
+
+func TestNewSysctlUnit(t *testing.T) {
+	for _, key := range []string{"", "net.ipv4 .ip_forward", "net.ipv4=1", "net/../../etc/passwd", "net....etc"} {
+		_, err := NewSysctl(key, "1")
+		assert.ErrorContains(t, err, "invalid sysctl key", key)
+	}
+
+	_, err := NewSysctl("vm.swappiness", "10\nkernel.panic = 1")
+	assert.ErrorContains(t, err, "invalid sysctl value")
+}
+
+func TestSysctlCheckIntegration(t *testing.T) {
+	setup := func(t *testing.T) (string, string) {
+		procRoot, dir := t.TempDir(), t.TempDir()
+
+		err := os.MkdirAll(filepath.Join(procRoot, "net/ipv4"), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.WriteFile(filepath.Join(procRoot, "net/ipv4/ip_local_port_range"), []byte("32768\t60999\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return procRoot, dir
+	}
+
+	t.Run("runtime and persisted values are fixed separately", func(t *testing.T) {
+		procRoot, dir := setup(t)
+		path := filepath.Join(dir, defaultSysctlFile)
+
+		err := os.WriteFile(path, []byte("# hardening\nvm.swappiness = 10\nnet.ipv4.ip_local_port_range = 1024 65535\n-net/ipv4/ip_local_port_range=2000 3000\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sysctl, err := NewSysctl("net.ipv4.ip_local_port_range", "32768 60999", WithProcRoot(procRoot), WithSysctlDir(dir))
+		assert.NoError(t, err)
+
+		actual, err := sysctl.Check()
+		assertCorrections(t, []Correction{sysctl.persist}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, sysctl.persist())
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "# hardening\nvm.swappiness = 10\nnet.ipv4.ip_local_port_range = 32768 60999\n", string(content))
+
+		corrections, err := sysctl.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		sysctl, err = NewSysctl("net/ipv4/ip_local_port_range", "1024 65535", WithProcRoot(procRoot), WithSysctlDir(dir))
+		assert.NoError(t, err)
+
+		actual, err = sysctl.Check()
+		assertCorrections(t, []Correction{sysctl.applyRuntime, sysctl.persist}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, sysctl.applyRuntime())
+
+		actual, err = sysctl.Check()
+		assertCorrections(t, []Correction{sysctl.persist}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+	})
+
+	t.Run("missing file is created", func(t *testing.T) {
+		procRoot, dir := setup(t)
+		sysctl, err := NewSysctl("net.ipv4.ip_local_port_range", "32768 60999", WithProcRoot(procRoot), WithSysctlDir(dir), WithSysctlFile("10-ports.conf"))
+		assert.NoError(t, err)
+
+		actual, err := sysctl.Check()
+		assertCorrections(t, []Correction{sysctl.persist}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, sysctl.persist())
+
+		content, err := os.ReadFile(filepath.Join(dir, "10-ports.conf"))
+		assert.NoError(t, err)
+		assert.Equal(t, "net.ipv4.ip_local_port_range = 32768 60999\n", string(content))
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		procRoot, dir := setup(t)
+		sysctl, err := NewSysctl("net.ipv4.missing", "1", WithProcRoot(procRoot), WithSysctlDir(dir))
+		assert.NoError(t, err)
+
+		corrections, err := sysctl.Check()
+		assert.Nil(t, corrections)
+		assert.ErrorContains(t, err, "unknown sysctl key: net.ipv4.missing")
+	})
+}
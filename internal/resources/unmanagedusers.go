@@ -0,0 +1,291 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/user"
+	"slices"
+	"time"
+
+	"github.com/scherepiuk/align/internal/logger"
+	"github.com/scherepiuk/align/internal/types"
+)
+
+// UnmanagedAction tells what is done to the unmanaged users.
+type UnmanagedAction int
+
+const (
+	UnmanagedReport UnmanagedAction = iota
+	UnmanagedLock
+	UnmanagedDelete
+)
+
+func (a UnmanagedAction) String() string {
+	switch a {
+	case UnmanagedReport:
+		return "report"
+	case UnmanagedLock:
+		return "lock"
+	case UnmanagedDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// UnmanagedUsers finds the local users in the uid range that are neither
+// managed (declared as a User, see LinkAccounts) nor allowed (see
+// WithAllowedUsers). They are only reported by default, and can be locked or
+// deleted instead (see WithUnmanagedAction).
+type UnmanagedUsers struct {
+	BaseDependant
+	uids    idRange
+	managed []string
+	allowed []string
+	action  UnmanagedAction
+
+	backend      AccountBackend
+	pollInterval time.Duration
+}
+
+func NewUnmanagedUsers(min, max int, opts ...UnmanagedUsersOption) *UnmanagedUsers {
+	unmanaged := &UnmanagedUsers{
+		uids:    idRange{min: min, max: max},
+		backend: NewShadowUtilsBackend(),
+	}
+
+	for _, opt := range opts {
+		opt(unmanaged)
+	}
+
+	return unmanaged
+}
+
+type UnmanagedUsersOption func(unmanaged *UnmanagedUsers)
+
+// WithManagedUsers lists the managed users. The watcher lists all the Users it
+// is given anyway (see LinkAccounts), so it's only needed when UnmanagedUsers
+// is used on its own.
+func WithManagedUsers(users ...*User) UnmanagedUsersOption {
+	return func(unmanaged *UnmanagedUsers) {
+		for _, user := range users {
+			if slices.Contains(unmanaged.managed, user.name) {
+				continue
+			}
+
+			logger.Global().Info("specifying managed user", "id", unmanaged.Id(), "name", user.name)
+			unmanaged.managed = append(unmanaged.managed, user.name)
+		}
+	}
+}
+
+// WithAllowedUsers lists the users that are left alone even though they are
+// not managed, e.g. the ones created by hand or by other tools.
+func WithAllowedUsers(names ...string) UnmanagedUsersOption {
+	return func(unmanaged *UnmanagedUsers) {
+		logger.Global().Info("specifying allowed users", "id", unmanaged.Id(), "names", names)
+		unmanaged.allowed = append(unmanaged.allowed, names...)
+	}
+}
+
+func WithUnmanagedAction(action UnmanagedAction) UnmanagedUsersOption {
+	return func(unmanaged *UnmanagedUsers) {
+		logger.Global().Info("specifying unmanaged users action", "id", unmanaged.Id(), "action", action)
+		unmanaged.action = action
+	}
+}
+
+func WithUnmanagedBackend(backend AccountBackend) UnmanagedUsersOption {
+	return func(unmanaged *UnmanagedUsers) {
+		logger.Global().Info("specifying unmanaged users backend", "id", unmanaged.Id())
+		unmanaged.backend = backend
+	}
+}
+
+// WithUnmanagedPolling re-checks the users periodically, on top of watching
+// the account databases (see User.Watch).
+func WithUnmanagedPolling(interval time.Duration) UnmanagedUsersOption {
+	return func(unmanaged *UnmanagedUsers) {
+		logger.Global().Info("specifying unmanaged users polling", "id", unmanaged.Id(), "interval", interval)
+		unmanaged.pollInterval = interval
+	}
+}
+
+func (u *UnmanagedUsers) Id() string {
+	return fmt.Sprintf("unmanaged-users:%d-%d", u.uids.min, u.uids.max)
+}
+
+// Check reports the unmanaged users as drift. In the report mode there is
+// nothing to correct, so no corrections are returned.
+func (u *UnmanagedUsers) Check() ([]Correction, error) {
+	entries, err := u.unmanaged()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	for _, entry := range entries {
+		logger.Global().Warn(
+			"user is not managed", "name", entry.name, "uid", entry.uid, "action", u.action,
+		)
+	}
+
+	switch u.action {
+	case UnmanagedLock:
+		return []Correction{u.lock}, ErrUnalignedResource
+	case UnmanagedDelete:
+		return []Correction{u.remove}, ErrUnalignedResource
+	default:
+		return nil, ErrUnalignedResource
+	}
+}
+
+// Watch re-checks the users whenever any of them changes (see
+// subscribeAccounts).
+func (u *UnmanagedUsers) Watch(
+	ctx context.Context,
+	correctionsCh chan<- []Correction,
+	errCh chan<- error,
+) {
+	err := check(u, correctionsCh)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	subscription, unsubscribe, err := subscribeAccounts(u.backend, anyAccount)
+	if err != nil {
+		errCh <- err
+		return
+	}
+	defer unsubscribe()
+
+	var pollCh <-chan time.Time
+	if u.pollInterval > 0 {
+		ticker := time.NewTicker(u.pollInterval)
+		defer ticker.Stop()
+		pollCh = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			errCh <- ctx.Err()
+			return
+
+		case err := <-subscription.errs:
+			errCh <- err
+			return
+
+		case <-subscription.changes:
+			err := check(u, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+		case <-pollCh:
+			err := check(u, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}
+}
+
+// unmanaged returns the users in the range that are neither managed nor
+// allowed. The users that are already locked and expired are skipped in the
+// lock mode, since there is nothing left to do to them.
+func (u *UnmanagedUsers) unmanaged() ([]passwdEntry, error) {
+	if u.uids.max < u.uids.min {
+		return nil, fmt.Errorf("invalid uid range: %d-%d", u.uids.min, u.uids.max)
+	}
+
+	entries, err := u.backend.listPasswd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	unmanaged := make([]passwdEntry, 0)
+	for _, entry := range entries {
+		// NOTE: root is never touched, even if the range includes uid 0.
+		if entry.uid == 0 || entry.uid < u.uids.min || entry.uid > u.uids.max {
+			continue
+		}
+
+		if slices.Contains(u.managed, entry.name) || slices.Contains(u.allowed, entry.name) {
+			continue
+		}
+
+		if u.action == UnmanagedLock {
+			disabled, err := u.disabled(entry.name)
+			if err != nil {
+				return nil, err
+			}
+
+			if disabled {
+				continue
+			}
+		}
+
+		unmanaged = append(unmanaged, entry)
+	}
+
+	return unmanaged, nil
+}
+
+func (u *UnmanagedUsers) disabled(name string) (bool, error) {
+	shadow, err := u.backend.lookupShadow(name)
+
+	if errors.Is(err, user.UnknownUserError(name)) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to lookup shadow entry: %w", err)
+	}
+
+	return shadow.locked() && shadow.expired(today()), nil
+}
+
+// lock locks and expires the users, so neither passwords nor keys can be used
+// to log in, while their files stay around for inspection.
+func (u *UnmanagedUsers) lock() error {
+	entries, err := u.unmanaged()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		changes := userChanges{locked: types.NewOptional(true), expired: types.NewOptional(true)}
+
+		err := u.backend.modifyUser(entry.name, changes)
+		if err != nil {
+			return fmt.Errorf("failed to lock unmanaged user: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// remove deletes the users, but keeps their homes.
+func (u *UnmanagedUsers) remove() error {
+	entries, err := u.unmanaged()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		err := u.backend.deleteUser(entry.name, false)
+		if err != nil {
+			return fmt.Errorf("failed to delete unmanaged user: %w", err)
+		}
+	}
+
+	return nil
+}
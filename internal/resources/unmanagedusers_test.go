@@ -0,0 +1,139 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmanagedUsersIntegration(t *testing.T) {
+	setup := func(t *testing.T) (AccountBackend, *User) {
+		root := testAccountsRoot(t)
+		backend := NewNativeBackend(root)
+
+		for _, name := range []string{"managed", "allowed", "intruder"} {
+			user := NewUser(name, WithUidRange(1000, 1999), WithGid(100), WithUserBackend(backend))
+			if err := user.create(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		system := NewUser("daemon", WithSystemUser(), WithGid(100), WithUserBackend(backend))
+		if err := system.create(); err != nil {
+			t.Fatal(err)
+		}
+
+		return backend, NewUser("managed", WithUserBackend(backend))
+	}
+
+	t.Run("unmanaged users are reported", func(t *testing.T) {
+		backend, managed := setup(t)
+		unmanaged := NewUnmanagedUsers(
+			1000, 1999,
+			WithManagedUsers(managed),
+			WithUnmanagedBackend(backend),
+		)
+
+		entries, err := unmanaged.unmanaged()
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+
+		corrections, err := unmanaged.Check()
+		assert.Nil(t, corrections)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		unmanaged = NewUnmanagedUsers(
+			1000, 1999,
+			WithManagedUsers(managed),
+			WithAllowedUsers("allowed", "intruder"),
+			WithUnmanagedBackend(backend),
+		)
+
+		corrections, err = unmanaged.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+	})
+
+	t.Run("declared users are managed", func(t *testing.T) {
+		backend, managed := setup(t)
+		unmanaged := NewUnmanagedUsers(1000, 1999, WithUnmanagedBackend(backend))
+
+		entries, err := unmanaged.unmanaged()
+		assert.NoError(t, err)
+		assert.Len(t, entries, 3)
+
+		assert.NoError(t, LinkAccounts(unmanaged, managed))
+
+		entries, err = unmanaged.unmanaged()
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+
+	t.Run("unmanaged users are locked", func(t *testing.T) {
+		backend, managed := setup(t)
+		unmanaged := NewUnmanagedUsers(
+			1000, 1999,
+			WithManagedUsers(managed),
+			WithAllowedUsers("allowed"),
+			WithUnmanagedAction(UnmanagedLock),
+			WithUnmanagedBackend(backend),
+		)
+
+		actual, err := unmanaged.Check()
+		assertCorrections(t, []Correction{unmanaged.lock}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, unmanaged.lock())
+
+		corrections, err := unmanaged.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		shadow, err := backend.lookupShadow("intruder")
+		assert.NoError(t, err)
+		assert.True(t, shadow.locked())
+		assert.True(t, shadow.expired(today()))
+
+		shadow, err = backend.lookupShadow("allowed")
+		assert.NoError(t, err)
+		assert.False(t, shadow.expired(today()))
+	})
+
+	t.Run("unmanaged users are deleted", func(t *testing.T) {
+		backend, managed := setup(t)
+		unmanaged := NewUnmanagedUsers(
+			0, 1999,
+			WithManagedUsers(managed),
+			WithAllowedUsers("allowed"),
+			WithUnmanagedAction(UnmanagedDelete),
+			WithUnmanagedBackend(backend),
+		)
+
+		actual, err := unmanaged.Check()
+		assertCorrections(t, []Correction{unmanaged.remove}, actual)
+		assert.ErrorIs(t, err, ErrUnalignedResource)
+
+		assert.NoError(t, unmanaged.remove())
+
+		corrections, err := unmanaged.Check()
+		assert.Nil(t, corrections)
+		assert.NoError(t, err)
+
+		entries, err := backend.listPasswd()
+		assert.NoError(t, err)
+
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			names = append(names, entry.name)
+		}
+		assert.Equal(t, []string{"root", "managed", "allowed"}, names)
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		unmanaged := NewUnmanagedUsers(2000, 1000, WithUnmanagedBackend(NewNativeBackend(t.TempDir())))
+
+		corrections, err := unmanaged.Check()
+		assert.Nil(t, corrections)
+		assert.ErrorContains(t, err, "invalid uid range")
+	})
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os/exec"
+	"os"
 	"os/user"
 	"slices"
 	"strings"
@@ -14,16 +14,49 @@ import (
 	"github.com/scherepiuk/align/internal/types"
 )
 
+const (
+	defaultHomeMode = os.FileMode(0o700)
+	secondsPerDay   = 24 * 60 * 60
+)
+
 type User struct {
 	BaseDependant
-	name   string
-	uid    int
-	gid    int
-	groups types.Optional[[]string]
+	name         string
+	uid          types.Optional[int]
+	uids         types.Optional[idRange]
+	gid          types.Optional[int]
+	primaryGroup types.Optional[string]
+	groups       types.Optional[[]string]
+	membership   Membership
+	claims       []string // the groups other resources make the user a member of
+	home         types.Optional[string]
+	shell        types.Optional[string]
+	comment      types.Optional[string]
+	password     types.Optional[string]
+	locked       types.Optional[bool]
+	expired      types.Optional[bool]
+	maxAge       types.Optional[int]
+	minAge       types.Optional[int]
+	warnDays     types.Optional[int]
+	expiry       types.Optional[int] // days since the epoch
+	system       bool
+
+	createHome bool
+	moveHome   bool
+
+	ensure     Ensure
+	removeHome bool
+
+	backend      AccountBackend
+	pollInterval time.Duration
 }
 
-func NewUser(name string, uid, gid int, opts ...UserOption) *User {
-	user := &User{name: name, uid: uid, gid: gid}
+// NewUser manages the user by its name. The uid is allocated when the user is
+// created, unless it is given (see WithUid). The same goes for the primary
+// group (see WithGid and WithPrimaryGroup): without one, the user gets a
+// private group named after it.
+func NewUser(name string, opts ...UserOption) *User {
+	user := &User{name: name, backend: NewShadowUtilsBackend()}
 
 	for _, opt := range opts {
 		opt(user)
@@ -34,6 +67,62 @@ func NewUser(name string, uid, gid int, opts ...UserOption) *User {
 
 type UserOption func(user *User)
 
+func WithUid(uid int) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user uid", "name", user.name, "uid", uid)
+		user.uid = types.NewOptional(uid)
+	}
+}
+
+// WithUidRange changes the range the uid (and the gid of the private group)
+// is allocated from, which is the regular (or the system, see WithSystemUser)
+// range by default.
+func WithUidRange(min, max int) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user uid range", "name", user.name, "min", min, "max", max)
+		user.uids = types.NewOptional(idRange{min: min, max: max})
+	}
+}
+
+func WithGid(gid int) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user gid", "name", user.name, "gid", gid)
+		user.gid = types.NewOptional(gid)
+		user.primaryGroup = types.Optional[string]{}
+	}
+}
+
+// WithPrimaryGroup sets the primary group by its name, the gid is resolved
+// whenever the user is checked.
+func WithPrimaryGroup(group string) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user primary group", "name", user.name, "group", group)
+		user.primaryGroup = types.NewOptional(group)
+		user.gid = types.Optional[int]{}
+	}
+}
+
+// WithManagedPrimaryGroup sets the primary group to the managed one, and makes
+// it a dependency of the user.
+func WithManagedPrimaryGroup(group *Group) UserOption {
+	return func(user *User) {
+		WithPrimaryGroup(group.name)(user)
+		user.SetDependencies(append(user.Dependencies(), group)...)
+	}
+}
+
+// WithMembership tells whether the groups (see WithGroups) are the only
+// supplementary groups of the user, or just the ones it has to be in. It is
+// additive by default. In the exact mode, the groups of the GroupMembership
+// resources listing the user (see WithManagedMembers and LinkAccounts) are
+// left alone as well.
+func WithMembership(membership Membership) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user membership", "name", user.name, "membership", membership)
+		user.membership = membership
+	}
+}
+
 func WithGroups(groups ...string) UserOption {
 	return func(user *User) {
 		logger.Global().Info("specifying user groups", "name", user.name, "groups", groups)
@@ -41,12 +130,159 @@ func WithGroups(groups ...string) UserOption {
 	}
 }
 
+func WithHome(home string) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user home", "name", user.name, "home", home)
+		user.home = types.NewOptional(home)
+	}
+}
+
+// WithCreateHome makes sure the home directory exists, creating it (owned by
+// the user) if it does not.
+func WithCreateHome() UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user home creation", "name", user.name)
+		user.createHome = true
+	}
+}
+
+// WithMoveHome moves the content of the current home directory to the new one
+// when the home directory is changed.
+func WithMoveHome() UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user home move", "name", user.name)
+		user.moveHome = true
+	}
+}
+
+func WithShell(shell string) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user shell", "name", user.name, "shell", shell)
+		user.shell = types.NewOptional(shell)
+	}
+}
+
+// WithComment sets the GECOS field of the user.
+func WithComment(comment string) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user comment", "name", user.name, "comment", comment)
+		user.comment = types.NewOptional(comment)
+	}
+}
+
+// WithPasswordHash sets the password hash (as in /etc/shadow, e.g.,
+// "$6$salt$..."). The hash itself is never logged.
+func WithPasswordHash(hash string) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user password hash", "name", user.name)
+		user.password = types.NewOptional(hash)
+	}
+}
+
+func WithLocked(locked bool) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user lock", "name", user.name, "locked", locked)
+		user.locked = types.NewOptional(locked)
+	}
+}
+
+func WithExpired(expired bool) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user expiration", "name", user.name, "expired", expired)
+		user.expired = types.NewOptional(expired)
+	}
+}
+
+// WithPasswordMaxAge sets the number of days after which the password has to
+// be changed.
+func WithPasswordMaxAge(days int) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user password max age", "name", user.name, "days", days)
+		user.maxAge = types.NewOptional(days)
+	}
+}
+
+// WithPasswordMinAge sets the number of days before the password can be
+// changed again.
+func WithPasswordMinAge(days int) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user password min age", "name", user.name, "days", days)
+		user.minAge = types.NewOptional(days)
+	}
+}
+
+// WithPasswordWarnDays sets the number of days the user is warned for before
+// the password expires.
+func WithPasswordWarnDays(days int) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user password warn days", "name", user.name, "days", days)
+		user.warnDays = types.NewOptional(days)
+	}
+}
+
+// WithAccountExpiry sets the date (in UTC) the account expires on. It sets
+// the same shadow field as WithExpired, so the two shouldn't be combined.
+func WithAccountExpiry(date time.Time) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user account expiry", "name", user.name, "date", date.Format(time.DateOnly))
+		user.expiry = types.NewOptional(epochDay(date))
+	}
+}
+
+// WithSystemUser creates the user as a system account. It only affects the
+// creation, an existing user is never converted.
+func WithSystemUser() UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying system user", "name", user.name)
+		user.system = true
+	}
+}
+
+func WithUserEnsure(ensure Ensure) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user ensure", "name", user.name, "ensure", ensure)
+		user.ensure = ensure
+	}
+}
+
+// WithRemoveHome makes the removal of the user (see WithUserEnsure) remove
+// its home directory and mail spool as well.
+func WithRemoveHome() UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user home removal", "name", user.name)
+		user.removeHome = true
+	}
+}
+
+// WithUserBackend changes how the user is read and changed, the shadow-utils
+// backend is used by default.
+func WithUserBackend(backend AccountBackend) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user backend", "name", user.name, "backend", fmt.Sprintf("%T", backend))
+		user.backend = backend
+	}
+}
+
+// WithUserPolling re-checks the user periodically on top of watching the
+// account databases. It's meant for users coming from NSS sources other than
+// files (e.g., LDAP), whose changes can't be watched.
+func WithUserPolling(interval time.Duration) UserOption {
+	return func(user *User) {
+		logger.Global().Info("specifying user polling", "name", user.name, "interval", interval)
+		user.pollInterval = interval
+	}
+}
+
 func (u *User) Id() string {
 	return u.name
 }
 
 func (u *User) Check() ([]Correction, error) {
-	uid, gid, groupIds, err := lookupUserDetails(u.name)
+	if u.ensure == EnsureAbsent {
+		return u.checkAbsent()
+	}
+
+	passwd, err := u.backend.lookupPasswd(u.name)
 
 	if errors.Is(err, user.UnknownUserError(u.name)) {
 		logger.Global().Warn("user does not exist", "name", u.name)
@@ -60,42 +296,116 @@ func (u *User) Check() ([]Correction, error) {
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to lookup user details: %w", err)
+		return nil, fmt.Errorf("failed to lookup passwd entry: %w", err)
 	}
 
 	corrections := make([]Correction, 0)
 
-	if uid != u.uid {
+	if u.uid.Ok() && passwd.uid != u.uid.Value() {
 		logger.Global().Warn(
 			"user has wrong uid", "name", u.name,
-			"uid.actual", uid, "uid.target", u.uid,
+			"uid.actual", passwd.uid, "uid.target", u.uid.Value(),
 		)
 		corrections = append(corrections, u.changeUid)
 	}
 
-	if gid != u.gid {
+	gid, err := u.primaryGid()
+	if err != nil {
+		return nil, err
+	}
+
+	if gid.Ok() && passwd.gid != gid.Value() {
 		logger.Global().Warn(
 			"user has wrong gid", "name", u.name,
-			"gid.actual", gid, "gid.target", u.gid,
+			"gid.actual", passwd.gid, "gid.target", gid.Value(),
 		)
 		corrections = append(corrections, u.changeGid)
 	}
 
 	if u.groups.Ok() {
-		for _, group := range u.groups.Value() {
-			gid, err := lookupGroup(group)
-			if err != nil {
-				return nil, fmt.Errorf("failed to lookup group: %w", err)
-			}
+		missing, extra, err := u.groupsDrift(passwd)
+		if err != nil {
+			return nil, err
+		}
 
-			if !slices.Contains(groupIds, gid) {
-				logger.Global().Warn("user is not member of group", "name", u.name, "group", group)
-				corrections = append(corrections, u.setGroups)
-				break
-			}
+		if len(missing) > 0 || len(extra) > 0 {
+			logger.Global().Warn(
+				"user has wrong groups", "name", u.name, "membership", u.membership,
+				"groups.missing", missing, "groups.extra", extra,
+			)
+			corrections = append(corrections, u.setGroups)
 		}
 	}
 
+	if u.home.Ok() && passwd.home != u.home.Value() {
+		logger.Global().Warn(
+			"user has wrong home", "name", u.name,
+			"home.actual", passwd.home, "home.target", u.home.Value(),
+		)
+		corrections = append(corrections, u.changeHome)
+	}
+
+	if u.createHome {
+		home := passwd.home
+		if u.home.Ok() {
+			home = u.home.Value()
+		}
+
+		_, err := os.Stat(u.backend.path(home))
+		if errors.Is(err, os.ErrNotExist) {
+			logger.Global().Warn("user's home does not exist", "name", u.name, "home", home)
+			corrections = append(corrections, u.createHomeDir)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to stat home: %w", err)
+		}
+	}
+
+	if u.shell.Ok() && passwd.shell != u.shell.Value() {
+		logger.Global().Warn(
+			"user has wrong shell", "name", u.name,
+			"shell.actual", passwd.shell, "shell.target", u.shell.Value(),
+		)
+		corrections = append(corrections, u.changeShell)
+	}
+
+	if u.comment.Ok() && passwd.gecos != u.comment.Value() {
+		logger.Global().Warn(
+			"user has wrong comment", "name", u.name,
+			"comment.actual", passwd.gecos, "comment.target", u.comment.Value(),
+		)
+		corrections = append(corrections, u.changeComment)
+	}
+
+	if u.password.Ok() || u.locked.Ok() || u.expired.Ok() || u.agingSpecified() {
+		shadow, err := u.backend.lookupShadow(u.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup shadow entry: %w", err)
+		}
+
+		if u.password.Ok() && strings.TrimPrefix(shadow.password, "!") != u.password.Value() {
+			logger.Global().Warn("user has wrong password hash", "name", u.name)
+			corrections = append(corrections, u.changePassword)
+		}
+
+		if u.locked.Ok() && shadow.locked() != u.locked.Value() {
+			logger.Global().Warn(
+				"user has wrong lock state", "name", u.name,
+				"locked.actual", shadow.locked(), "locked.target", u.locked.Value(),
+			)
+			corrections = append(corrections, u.changeLocked)
+		}
+
+		if u.expired.Ok() && shadow.expired(today()) != u.expired.Value() {
+			logger.Global().Warn(
+				"user has wrong expiration state", "name", u.name,
+				"expired.actual", shadow.expired(today()), "expired.target", u.expired.Value(),
+			)
+			corrections = append(corrections, u.changeExpired)
+		}
+
+		corrections = append(corrections, u.agingDrift(shadow)...)
+	}
+
 	if len(corrections) > 0 {
 		return corrections, ErrUnalignedResource
 	}
@@ -103,12 +413,128 @@ func (u *User) Check() ([]Correction, error) {
 	return nil, nil
 }
 
+func (u *User) agingSpecified() bool {
+	return u.maxAge.Ok() || u.minAge.Ok() || u.warnDays.Ok() || u.expiry.Ok()
+}
+
+// agingDrift returns a correction for every aging field of the shadow entry
+// that differs from the specified one.
+func (u *User) agingDrift(shadow shadowEntry) []Correction {
+	corrections := make([]Correction, 0)
+
+	if u.maxAge.Ok() && shadow.maxAge != u.maxAge {
+		logger.Global().Warn(
+			"user has wrong password max age", "name", u.name,
+			"maxAge.actual", shadow.maxAge, "maxAge.target", u.maxAge.Value(),
+		)
+		corrections = append(corrections, u.changeMaxAge)
+	}
+
+	if u.minAge.Ok() && shadow.minAge != u.minAge {
+		logger.Global().Warn(
+			"user has wrong password min age", "name", u.name,
+			"minAge.actual", shadow.minAge, "minAge.target", u.minAge.Value(),
+		)
+		corrections = append(corrections, u.changeMinAge)
+	}
+
+	if u.warnDays.Ok() && shadow.warnDays != u.warnDays {
+		logger.Global().Warn(
+			"user has wrong password warn days", "name", u.name,
+			"warnDays.actual", shadow.warnDays, "warnDays.target", u.warnDays.Value(),
+		)
+		corrections = append(corrections, u.changeWarnDays)
+	}
+
+	if u.expiry.Ok() && shadow.expire != u.expiry {
+		logger.Global().Warn(
+			"user has wrong account expiry", "name", u.name,
+			"expiry.actual", shadow.expire, "expiry.target", u.expiry.Value(),
+		)
+		corrections = append(corrections, u.changeExpiry)
+	}
+
+	return corrections
+}
+
+// groupsDrift returns the groups the user has to be added to, and the ones it
+// has to be removed from (always none in the additive mode).
+func (u *User) groupsDrift(passwd passwdEntry) ([]string, []string, error) {
+	entries, err := u.backend.listGroups()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+
+	missing := make([]string, 0)
+	for _, group := range u.groups.Value() {
+		i := slices.IndexFunc(entries, func(entry groupEntry) bool { return entry.name == group })
+		if i == -1 {
+			return nil, nil, fmt.Errorf("failed to lookup group: %w", user.UnknownGroupError(group))
+		}
+
+		if entries[i].gid != passwd.gid && !slices.Contains(entries[i].members, u.name) {
+			missing = append(missing, group)
+		}
+	}
+
+	extra := make([]string, 0)
+	if u.membership == MembershipExact {
+		for _, entry := range entries {
+			if !slices.Contains(entry.members, u.name) || slices.Contains(u.groups.Value(), entry.name) {
+				continue
+			}
+
+			if !u.claimed(entry.name) {
+				extra = append(extra, entry.name)
+			}
+		}
+	}
+
+	return missing, extra, nil
+}
+
+func (u *User) checkAbsent() ([]Correction, error) {
+	_, err := u.backend.lookupPasswd(u.name)
+
+	if errors.Is(err, user.UnknownUserError(u.name)) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup passwd entry: %w", err)
+	}
+
+	logger.Global().Warn("user exists but should be absent", "name", u.name)
+	return []Correction{u.remove}, ErrUnalignedResource
+}
+
+// Watch re-checks the user whenever its entries in the account databases
+// change (see subscribeAccounts). The databases are only watched as files, so
+// users coming from other NSS sources need polling (see WithUserPolling).
 func (u *User) Watch(
 	ctx context.Context,
 	correctionsCh chan<- []Correction,
 	errCh chan<- error,
 ) {
-	ticker := time.NewTicker(5 * time.Second)
+	err := check(u, correctionsCh)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	subscription, unsubscribe, err := subscribeAccounts(u.backend, u.name)
+	if err != nil {
+		errCh <- err
+		return
+	}
+	defer unsubscribe()
+
+	var pollCh <-chan time.Time
+	if u.pollInterval > 0 {
+		ticker := time.NewTicker(u.pollInterval)
+		defer ticker.Stop()
+		pollCh = ticker.C
+	}
 
 	for {
 		select {
@@ -116,14 +542,19 @@ func (u *User) Watch(
 			errCh <- ctx.Err()
 			return
 
-		case <-ticker.C:
-			corrections, err := u.Check()
+		case err := <-subscription.errs:
+			errCh <- err
+			return
 
-			if errors.Is(err, ErrUnalignedResource) {
-				correctionsCh <- corrections
-				continue
+		case <-subscription.changes:
+			err := check(u, correctionsCh)
+			if err != nil {
+				errCh <- err
+				return
 			}
 
+		case <-pollCh:
+			err := check(u, correctionsCh)
 			if err != nil {
 				errCh <- err
 				return
@@ -132,16 +563,120 @@ func (u *User) Watch(
 	}
 }
 
+// primaryGid returns the gid the user should have, if it's specified.
+func (u *User) primaryGid() (types.Optional[int], error) {
+	if !u.primaryGroup.Ok() {
+		return u.gid, nil
+	}
+
+	entry, err := u.backend.lookupGroup(u.primaryGroup.Value())
+	if err != nil {
+		return types.Optional[int]{}, fmt.Errorf("failed to lookup primary group: %w", err)
+	}
+
+	return types.NewOptional(entry.gid), nil
+}
+
+func (u *User) idRange() idRange {
+	if u.uids.Ok() {
+		return u.uids.Value()
+	}
+
+	if u.system {
+		return systemIds
+	}
+
+	return regularIds
+}
+
+func (u *User) allocateUid() (int, error) {
+	entries, err := u.backend.listPasswd()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	used := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		used = append(used, entry.uid)
+	}
+
+	uid, err := allocateId(u.name, u.idRange(), used)
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate uid: %w", err)
+	}
+
+	return uid, nil
+}
+
+// privateGid returns the gid of the group named after the user, the group is
+// created (preferably with the same gid as the uid) if it does not exist.
+func (u *User) privateGid(uid int) (int, error) {
+	entry, err := u.backend.lookupGroup(u.name)
+	if err == nil {
+		return entry.gid, nil
+	}
+
+	if !errors.Is(err, user.UnknownGroupError(u.name)) {
+		return 0, fmt.Errorf("failed to lookup private group: %w", err)
+	}
+
+	gid, err := allocateGroupId(u.backend, u.name, u.idRange(), uid)
+	if err != nil {
+		return 0, err
+	}
+
+	err = u.backend.addGroup(u.name, groupChanges{gid: types.NewOptional(gid), system: u.system})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create private group: %w", err)
+	}
+
+	return gid, nil
+}
+
 func (u *User) create() error {
-	cmd := exec.Command(
-		"useradd",
-		"-u", fmt.Sprint(u.uid),
-		"-g", fmt.Sprint(u.gid),
-		"-G", strings.Join(u.groups.Value(), ","),
-		u.name,
-	)
+	uid := u.uid
+	if !uid.Ok() {
+		allocated, err := u.allocateUid()
+		if err != nil {
+			return err
+		}
+
+		uid = types.NewOptional(allocated)
+	}
+
+	gid, err := u.primaryGid()
+	if err != nil {
+		return err
+	}
+
+	if !gid.Ok() {
+		private, err := u.privateGid(uid.Value())
+		if err != nil {
+			return err
+		}
+
+		gid = types.NewOptional(private)
+	}
+
+	changes := userChanges{
+		uid:        uid,
+		gid:        gid,
+		groups:     u.groups,
+		home:       u.home,
+		createHome: u.createHome,
+		shell:      u.shell,
+		comment:    u.comment,
+		password:   u.password,
+		locked:     u.locked,
+		expired:    u.expired,
+		maxAge:     u.maxAge,
+		minAge:     u.minAge,
+		warnDays:   u.warnDays,
+		expiry:     u.expiry,
+		system:     u.system,
+	}
 
-	err := cmd.Run()
+	err = u.backend.addUser(u.name, changes)
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
@@ -149,10 +684,21 @@ func (u *User) create() error {
 	return nil
 }
 
+func (u *User) remove() error {
+	err := u.backend.deleteUser(u.name, u.removeHome)
+	if err != nil {
+		return fmt.Errorf("failed to remove user: %w", err)
+	}
+
+	return nil
+}
+
 func (u *User) changeUid() error {
-	cmd := exec.Command("usermod", "-u", fmt.Sprint(u.uid), u.name)
+	if !u.uid.Ok() {
+		return nil
+	}
 
-	err := cmd.Run()
+	err := u.backend.modifyUser(u.name, userChanges{uid: u.uid})
 	if err != nil {
 		return fmt.Errorf("failed to change user's uid: %w", err)
 	}
@@ -161,9 +707,16 @@ func (u *User) changeUid() error {
 }
 
 func (u *User) changeGid() error {
-	cmd := exec.Command("usermod", "-g", fmt.Sprint(u.gid), u.name)
+	gid, err := u.primaryGid()
+	if err != nil {
+		return err
+	}
 
-	err := cmd.Run()
+	if !gid.Ok() {
+		return nil
+	}
+
+	err = u.backend.modifyUser(u.name, userChanges{gid: gid})
 	if err != nil {
 		return fmt.Errorf("failed to change user's gid: %w", err)
 	}
@@ -176,12 +729,196 @@ func (u *User) setGroups() error {
 		return nil
 	}
 
-	cmd := exec.Command("usermod", "-G", strings.Join(u.groups.Value(), ","), u.name)
+	changes := userChanges{groups: u.groups, addGroups: u.membership == MembershipAdditive}
+
+	if u.membership == MembershipExact {
+		groups, err := u.claimedGroups()
+		if err != nil {
+			return err
+		}
+
+		changes.groups = types.NewOptional(append(slices.Clone(u.groups.Value()), groups...))
+	}
 
-	err := cmd.Run()
+	err := u.backend.modifyUser(u.name, changes)
 	if err != nil {
 		return fmt.Errorf("failed to set user's groups: %w", err)
 	}
 
 	return nil
 }
+
+// claimedGroups returns the groups the user is in thanks to other resources,
+// which replacing the groups in the exact mode has to keep.
+func (u *User) claimedGroups() ([]string, error) {
+	entries, err := u.backend.listGroups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+
+	claimed := make([]string, 0)
+	for _, entry := range entries {
+		if slices.Contains(u.groups.Value(), entry.name) || !slices.Contains(entry.members, u.name) {
+			continue
+		}
+
+		if u.claimed(entry.name) {
+			claimed = append(claimed, entry.name)
+		}
+	}
+
+	return claimed, nil
+}
+
+// claimed tells whether the user is in the group thanks to another resource.
+func (u *User) claimed(group string) bool {
+	return slices.Contains(u.claims, group)
+}
+
+// claim makes the group one the user is in thanks to another resource.
+func (u *User) claim(group string) {
+	if !u.claimed(group) {
+		u.claims = append(u.claims, group)
+	}
+}
+
+func (u *User) changeHome() error {
+	err := u.backend.modifyUser(u.name, userChanges{home: u.home, moveHome: u.moveHome})
+	if err != nil {
+		return fmt.Errorf("failed to change user's home: %w", err)
+	}
+
+	return nil
+}
+
+func (u *User) createHomeDir() error {
+	err := u.backend.modifyUser(u.name, userChanges{createHome: true})
+	if err != nil {
+		return fmt.Errorf("failed to create user's home: %w", err)
+	}
+
+	return nil
+}
+
+func (u *User) changeShell() error {
+	err := u.backend.modifyUser(u.name, userChanges{shell: u.shell})
+	if err != nil {
+		return fmt.Errorf("failed to change user's shell: %w", err)
+	}
+
+	return nil
+}
+
+func (u *User) changeComment() error {
+	err := u.backend.modifyUser(u.name, userChanges{comment: u.comment})
+	if err != nil {
+		return fmt.Errorf("failed to change user's comment: %w", err)
+	}
+
+	return nil
+}
+
+func (u *User) changePassword() error {
+	shadow, err := u.backend.lookupShadow(u.name)
+	if err != nil {
+		return fmt.Errorf("failed to lookup shadow entry: %w", err)
+	}
+
+	// NOTE: Setting the hash would otherwise silently unlock the user.
+	hash := u.password.Value()
+	if shadow.locked() {
+		hash = "!" + hash
+	}
+
+	err = u.backend.modifyUser(u.name, userChanges{password: types.NewOptional(hash)})
+	if err != nil {
+		return fmt.Errorf("failed to change user's password: %w", err)
+	}
+
+	return nil
+}
+
+func (u *User) changeLocked() error {
+	if !u.locked.Ok() {
+		return nil
+	}
+
+	err := u.backend.modifyUser(u.name, userChanges{locked: u.locked})
+	if err != nil {
+		return fmt.Errorf("failed to change user's lock state: %w", err)
+	}
+
+	return nil
+}
+
+func (u *User) changeExpired() error {
+	if !u.expired.Ok() {
+		return nil
+	}
+
+	err := u.backend.modifyUser(u.name, userChanges{expired: u.expired})
+	if err != nil {
+		return fmt.Errorf("failed to change user's expiration state: %w", err)
+	}
+
+	return nil
+}
+
+func (u *User) changeMaxAge() error {
+	if !u.maxAge.Ok() {
+		return nil
+	}
+
+	err := u.backend.modifyUser(u.name, userChanges{maxAge: u.maxAge})
+	if err != nil {
+		return fmt.Errorf("failed to change user's password max age: %w", err)
+	}
+
+	return nil
+}
+
+func (u *User) changeMinAge() error {
+	if !u.minAge.Ok() {
+		return nil
+	}
+
+	err := u.backend.modifyUser(u.name, userChanges{minAge: u.minAge})
+	if err != nil {
+		return fmt.Errorf("failed to change user's password min age: %w", err)
+	}
+
+	return nil
+}
+
+func (u *User) changeWarnDays() error {
+	if !u.warnDays.Ok() {
+		return nil
+	}
+
+	err := u.backend.modifyUser(u.name, userChanges{warnDays: u.warnDays})
+	if err != nil {
+		return fmt.Errorf("failed to change user's password warn days: %w", err)
+	}
+
+	return nil
+}
+
+func (u *User) changeExpiry() error {
+	if !u.expiry.Ok() {
+		return nil
+	}
+
+	err := u.backend.modifyUser(u.name, userChanges{expiry: u.expiry})
+	if err != nil {
+		return fmt.Errorf("failed to change user's account expiry: %w", err)
+	}
+
+	return nil
+}
+
+// epochDay returns the number of days since the epoch, the way dates are
+// stored in /etc/shadow.
+func epochDay(date time.Time) int {
+	year, month, day := date.Date()
+	return int(time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / secondsPerDay)
+}
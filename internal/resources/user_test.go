@@ -0,0 +1,140 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/scherepiuk/align/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUserUnit(t *testing.T) {
+	t.Run("new user without options", func(t *testing.T) {
+		user := NewUser("testing")
+
+		assert.Equal(t, "testing", user.Id())
+		assert.Equal(t, types.Optional[int]{}, user.uid)
+		assert.Equal(t, types.Optional[int]{}, user.gid)
+		assert.Equal(t, types.Optional[string]{}, user.primaryGroup)
+		assert.Equal(t, types.Optional[string]{}, user.home)
+		assert.Equal(t, types.Optional[string]{}, user.shell)
+		assert.Equal(t, types.Optional[bool]{}, user.locked)
+		assert.False(t, user.createHome)
+		assert.False(t, user.system)
+	})
+
+	t.Run("new user with account attributes", func(t *testing.T) {
+		user := NewUser(
+			"testing",
+			WithUid(42069),
+			WithGid(1000),
+			WithHome("/srv/testing"),
+			WithCreateHome(),
+			WithShell("/bin/bash"),
+			WithComment("Testing User"),
+			WithPasswordHash("$6$salt$hash"),
+			WithLocked(true),
+			WithExpired(false),
+			WithSystemUser(),
+		)
+
+		assert.Equal(t, types.NewOptional(42069), user.uid)
+		assert.Equal(t, types.NewOptional(1000), user.gid)
+		assert.Equal(t, types.NewOptional("/srv/testing"), user.home)
+		assert.True(t, user.createHome)
+		assert.False(t, user.moveHome)
+		assert.Equal(t, types.NewOptional("/bin/bash"), user.shell)
+		assert.Equal(t, types.NewOptional("Testing User"), user.comment)
+		assert.Equal(t, types.NewOptional("$6$salt$hash"), user.password)
+		assert.Equal(t, types.NewOptional(true), user.locked)
+		assert.Equal(t, types.NewOptional(false), user.expired)
+		assert.True(t, user.system)
+	})
+
+	t.Run("new user with primary group", func(t *testing.T) {
+		group := NewGroup("developers")
+		user := NewUser("testing", WithGid(1000), WithManagedPrimaryGroup(group))
+
+		assert.Equal(t, types.Optional[int]{}, user.gid)
+		assert.Equal(t, types.NewOptional("developers"), user.primaryGroup)
+		assert.Equal(t, []Resource{group}, user.Dependencies())
+	})
+}
+
+func TestAllocateIdUnit(t *testing.T) {
+	t.Run("id is stable", func(t *testing.T) {
+		first, err := allocateId("testing", regularIds, []int{})
+		assert.NoError(t, err)
+
+		second, err := allocateId("testing", regularIds, []int{1000, 1001})
+		assert.NoError(t, err)
+
+		assert.Equal(t, first, second)
+		assert.GreaterOrEqual(t, first, regularIds.min)
+		assert.LessOrEqual(t, first, regularIds.max)
+	})
+
+	t.Run("used ids are skipped", func(t *testing.T) {
+		ids := idRange{min: 100, max: 102}
+
+		first, err := allocateId("testing", ids, []int{})
+		assert.NoError(t, err)
+
+		second, err := allocateId("testing", ids, []int{first})
+		assert.NoError(t, err)
+		assert.NotEqual(t, first, second)
+
+		_, err = allocateId("testing", ids, []int{100, 101, 102})
+		assert.ErrorContains(t, err, "no free ids")
+	})
+}
+
+func TestParsePasswdEntryUnit(t *testing.T) {
+	t.Run("passwd entry", func(t *testing.T) {
+		entry, err := parsePasswdEntry("testing:x:42069:1000:Testing User,,,:/home/testing:/bin/bash")
+
+		assert.NoError(t, err)
+		assert.Equal(t, passwdEntry{
+			name:  "testing",
+			uid:   42069,
+			gid:   1000,
+			gecos: "Testing User,,,",
+			home:  "/home/testing",
+			shell: "/bin/bash",
+		}, entry)
+	})
+
+	t.Run("malformed passwd entry", func(t *testing.T) {
+		_, err := parsePasswdEntry("testing:x:42069:1000")
+
+		assert.ErrorContains(t, err, "malformed passwd entry")
+	})
+}
+
+func TestParseShadowEntryUnit(t *testing.T) {
+	t.Run("shadow entry with empty fields", func(t *testing.T) {
+		entry, err := parseShadowEntry("testing:!$6$salt$hash:19000:0:99999:7:::")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "!$6$salt$hash", entry.password)
+		assert.Equal(t, types.NewOptional(19000), entry.lastChange)
+		assert.Equal(t, types.NewOptional(99999), entry.maxAge)
+		assert.Equal(t, types.Optional[int]{}, entry.inactive)
+		assert.Equal(t, types.Optional[int]{}, entry.expire)
+		assert.True(t, entry.locked())
+		assert.False(t, entry.expired(20000))
+	})
+
+	t.Run("expired shadow entry", func(t *testing.T) {
+		entry, err := parseShadowEntry("testing:$6$salt$hash:19000:::::1:")
+
+		assert.NoError(t, err)
+		assert.False(t, entry.locked())
+		assert.True(t, entry.expired(20000))
+	})
+
+	t.Run("malformed shadow entry", func(t *testing.T) {
+		_, err := parseShadowEntry("testing:x:19000")
+
+		assert.ErrorContains(t, err, "malformed shadow entry")
+	})
+}
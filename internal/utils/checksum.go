@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+func Checksum(r io.Reader) (string, error) {
+	hash := sha256.New()
+
+	_, err := io.Copy(hash, r)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute checksum: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func ChecksumFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	return Checksum(file)
+}
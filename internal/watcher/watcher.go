@@ -13,8 +13,13 @@ type resourceWatcher struct {
 	dependencyLayers [][]resources.Resource
 }
 
-func NewResourceWatcher(resources ...resources.Resource) (*resourceWatcher, error) {
-	layers, err := sortTopologically(resources)
+func NewResourceWatcher(rs ...resources.Resource) (*resourceWatcher, error) {
+	err := resources.LinkAccounts(rs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to link account resources: %w", err)
+	}
+
+	layers, err := sortTopologically(rs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to construct dependency graph: %w", err)
 	}
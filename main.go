@@ -31,8 +31,11 @@ func main() {
 }
 
 func expectedResources() []resources.Resource {
+	alignGroup := resources.NewGroup("align-testing-group")
+
 	alignUser := resources.NewUser(
-		"align-testing-user", 42069, 1000,
+		"align-testing-user",
+		resources.WithManagedPrimaryGroup(alignGroup),
 		resources.WithGroups("root", "wheel"),
 	)
 
@@ -40,10 +43,10 @@ func expectedResources() []resources.Resource {
 		"/tmp/align-testing-file",
 		resources.WithMode(os.FileMode(0o664)),
 		resources.WithOwner("align-testing-user"),
-		resources.WithGroup("scherepiuk"),
+		resources.WithGroup("align-testing-group"),
 	)
 
-	alignFile.SetDependencies(alignUser)
+	alignFile.SetDependencies(alignUser, alignGroup)
 
-	return []resources.Resource{alignFile, alignUser}
+	return []resources.Resource{alignFile, alignUser, alignGroup}
 }